@@ -12,6 +12,26 @@ type ServerRunOptions struct {
 	ServerDbPassword string
 	ServerKubeIp     string
 	ServerKubePort   string
+	ServerSpoolDir   string
+	ServerSpoolMax   string
+	ServerAnnotations string
+	ServerCollectors  string
+	ServerGeneric     string
+	ServerProtobuf    string
+	ServerRecord      string
+	ServerReplay      string
+	ServerCluster     string
+	ServerElection    string
+	ServerElectionLock string
+	ServerIdentity    string
+	ServerShard       string
+	ServerShutdownTimeout string
+	ServerLivenessIntervals string
+	ServerAuth string
+	ServerTLSCert string
+	ServerTLSKey string
+	ServerTLSClientCA string
+	ServerAuditFile string
 }
 type env struct {
 	envDbType     string
@@ -20,6 +40,26 @@ type env struct {
 	envDbPassword string
 	envKubeIp     string
 	envKubePort   string
+	envSpoolDir   string
+	envSpoolMax   string
+	envAnnotations string
+	envCollectors  string
+	envGeneric     string
+	envProtobuf    string
+	envRecord      string
+	envReplay      string
+	envCluster     string
+	envElection    string
+	envElectionLock string
+	envIdentity    string
+	envShard       string
+	envShutdownTimeout string
+	envLivenessIntervals string
+	envAuth string
+	envTLSCert string
+	envTLSKey string
+	envTLSClientCA string
+	envAuditFile string
 }
 
 func getOsEnv() (NewEnv env) {
@@ -30,14 +70,32 @@ func getOsEnv() (NewEnv env) {
 		envDbPassword: os.Getenv("DBPASSWORD"),
 		envKubeIp:     os.Getenv("KUBEIP"),
 		envKubePort:   os.Getenv("KUBEPORT"),
+		envSpoolDir:   os.Getenv("SPOOLDIR"),
+		envSpoolMax:   os.Getenv("SPOOLMAX"),
+		envAnnotations: os.Getenv("ANNOTATIONS"),
+		envCollectors:  os.Getenv("COLLECTORS"),
+		envGeneric:     os.Getenv("GENERIC"),
+		envProtobuf:    os.Getenv("PROTOBUF"),
+		envRecord:      os.Getenv("RECORD"),
+		envReplay:      os.Getenv("REPLAY"),
+		envCluster:     os.Getenv("CLUSTER"),
+		envElection:    os.Getenv("ELECTION"),
+		envElectionLock: os.Getenv("ELECTIONLOCK"),
+		envIdentity:    os.Getenv("IDENTITY"),
+		envShard:       os.Getenv("SHARD"),
+		envShutdownTimeout: os.Getenv("SHUTDOWNTIMEOUT"),
+		envLivenessIntervals: os.Getenv("LIVENESSINTERVALS"),
+		envAuth: os.Getenv("AUTH"),
+		envTLSCert: os.Getenv("TLSCERT"),
+		envTLSKey: os.Getenv("TLSKEY"),
+		envTLSClientCA: os.Getenv("TLSCLIENTCA"),
+		envAuditFile: os.Getenv("AUDITFILE"),
 	}
 	return
 }
 
 func preCmdFlag(vName string, vValue string, usage string) *string {
-	s := flag.String(vName, vValue, usage)
-	flag.Parse()
-	return s
+	return flag.String(vName, vValue, usage)
 }
 
 func getRunFlag() map[string]*string {
@@ -48,6 +106,26 @@ func getRunFlag() map[string]*string {
 	runFlag["DbPort"] = preCmdFlag("dbport", "non", "input the database port")
 	runFlag["KubeIp"] = preCmdFlag("kubeip", "non", "input the KubeAPIserver ip address")
 	runFlag["KubePort"] = preCmdFlag("kubeport", "non", "input the kubeAPIserver port")
+	runFlag["SpoolDir"] = preCmdFlag("spooldir", "non", "input the directory failed inserts are spooled to")
+	runFlag["SpoolMax"] = preCmdFlag("spoolmax", "non", "input the spool size limit in MB")
+	runFlag["Election"] = preCmdFlag("election", "non", "input the leader election lock: off, mysql, endpoints or configmaps")
+	runFlag["ElectionLock"] = preCmdFlag("electionlock", "non", "input the leader election lock name, namespace/name for endpoints and configmaps")
+	runFlag["Identity"] = preCmdFlag("identity", "non", "input the name of this replica in the leader election and the shards")
+	runFlag["Shard"] = preCmdFlag("shard", "non", "input on to split the namespaces between the replicas")
+	runFlag["ShutdownTimeout"] = preCmdFlag("shutdowntimeout", "non", "input the seconds to wait for the cycle in flight when stopping")
+	runFlag["LivenessIntervals"] = preCmdFlag("livenessintervals", "non", "input the collect loop intervals after which a stuck loop or cycle fails /healthz")
+	runFlag["Auth"] = preCmdFlag("auth", "non", "input the auth config file with the API tokens, off to let anyone call the REST API")
+	runFlag["TLSCert"] = preCmdFlag("tlscert", "non", "input the certificate file to serve the REST API over TLS")
+	runFlag["TLSKey"] = preCmdFlag("tlskey", "non", "input the key file of tlscert")
+	runFlag["TLSClientCA"] = preCmdFlag("tlsclientca", "non", "input the CA bundle client certificates are verified against")
+	runFlag["AuditFile"] = preCmdFlag("auditfile", "non", "input the file the audit log is mirrored to as JSON lines")
+	runFlag["Cluster"] = preCmdFlag("cluster", "non", "input the cluster name recorded with every run")
+	runFlag["Record"] = preCmdFlag("record", "non", "input the cassette directory the KubeAPIserver responses are recorded to")
+	runFlag["Replay"] = preCmdFlag("replay", "non", "input the cassette directory collected instead of the KubeAPIserver")
+	runFlag["Protobuf"] = preCmdFlag("protobuf", "non", "input on to ask the KubeAPIserver for protobuf lists")
+	runFlag["Generic"] = preCmdFlag("generic", "non", "input the JSON file listing the resources collected as documents")
+	runFlag["Collectors"] = preCmdFlag("collectors", "non", "input the comma separated kinds to collect, -kind disables a kind")
+	runFlag["Annotations"] = preCmdFlag("annotations", "non", "input the comma separated annotation keys to store, a trailing * matches a prefix")
 	return runFlag
 }
 
@@ -55,9 +133,10 @@ var RunFlag ServerRunOptions
 
 func preFlag() {
 	runFlag := getRunFlag()
+	// parse only once every flag is defined, parsing earlier rejects the later ones
+	flag.Parse()
 	osEnv := getOsEnv()
 	for k, v := range runFlag {
-		flag.Parse()
 		switch k {
 		case "DbType":
 			common.DebugPrint(k, *v)
@@ -76,17 +155,83 @@ func preFlag() {
 			}
 		case "KubeIp":
 			common.DebugPrint(k, *v)
-			if *v == "non" {
-				RunFlag.ServerKubeIp = osEnv.envKubeIp
-			}
+			RunFlag.ServerKubeIp = flagOrEnv(*v, osEnv.envKubeIp, "")
 		case "KubePort":
 			common.DebugPrint(k, *v)
-			if *v == "non" {
-				RunFlag.ServerKubePort = osEnv.envKubePort
-			}
+			RunFlag.ServerKubePort = flagOrEnv(*v, osEnv.envKubePort, "")
+		case "SpoolDir":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerSpoolDir = flagOrEnv(*v, osEnv.envSpoolDir, "spool")
+		case "SpoolMax":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerSpoolMax = flagOrEnv(*v, osEnv.envSpoolMax, "256")
+		case "Generic":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerGeneric = flagOrEnv(*v, osEnv.envGeneric, "")
+		case "Election":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerElection = flagOrEnv(*v, osEnv.envElection, "off")
+		case "ElectionLock":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerElectionLock = flagOrEnv(*v, osEnv.envElectionLock, "default/jobplatform-collector")
+		case "Identity":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerIdentity = flagOrEnv(*v, osEnv.envIdentity, "")
+		case "Shard":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerShard = flagOrEnv(*v, osEnv.envShard, "off")
+		case "ShutdownTimeout":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerShutdownTimeout = flagOrEnv(*v, osEnv.envShutdownTimeout, "30")
+		case "LivenessIntervals":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerLivenessIntervals = flagOrEnv(*v, osEnv.envLivenessIntervals, "24")
+		case "Auth":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerAuth = flagOrEnv(*v, osEnv.envAuth, "off")
+		case "TLSCert":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerTLSCert = flagOrEnv(*v, osEnv.envTLSCert, "")
+		case "TLSKey":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerTLSKey = flagOrEnv(*v, osEnv.envTLSKey, "")
+		case "TLSClientCA":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerTLSClientCA = flagOrEnv(*v, osEnv.envTLSClientCA, "")
+		case "AuditFile":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerAuditFile = flagOrEnv(*v, osEnv.envAuditFile, "")
+		case "Cluster":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerCluster = flagOrEnv(*v, osEnv.envCluster, "")
+		case "Record":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerRecord = flagOrEnv(*v, osEnv.envRecord, "")
+		case "Replay":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerReplay = flagOrEnv(*v, osEnv.envReplay, "")
+		case "Protobuf":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerProtobuf = flagOrEnv(*v, osEnv.envProtobuf, "off")
+		case "Collectors":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerCollectors = flagOrEnv(*v, osEnv.envCollectors, "")
+		case "Annotations":
+			common.DebugPrint(k, *v)
+			RunFlag.ServerAnnotations = flagOrEnv(*v, osEnv.envAnnotations, "")
 		}
 	}
 }
+func flagOrEnv(flagValue string, envValue string, defaultValue string) string {
+	if flagValue != "non" {
+		return flagValue
+	}
+	if envValue != "" {
+		return envValue
+	}
+	return defaultValue
+}
+
 func init() {
 	preFlag()
 }
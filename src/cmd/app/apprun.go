@@ -1,10 +1,14 @@
 package app
 
 import (
+	"context"
 	"service/collect"
 	"sync"
 	"time"
 	"common"
+	"dao"
+	"strconv"
+	"strings"
 )
 
 //var Switch *bool
@@ -15,6 +19,21 @@ var statusSwitchOn chan bool
 var statusSwitchOff chan bool
 var statusSwitchLast *bool
 
+// cycles counts the cycles in flight. stopCollect stops the collect loop;
+// stopBackground, closed once the cycles drained, stops the spool, the
+// election and the shard.
+var cycles sync.WaitGroup
+var background sync.WaitGroup
+var stopCollect = make(chan struct{})
+var stopBackground = make(chan struct{})
+var stopOnce sync.Once
+
+// cycleLock orders the start of a cycle against Shutdown, so none starts
+// once Shutdown waits for the cycles; cycleRunning is set while one is in
+// flight.
+var cycleLock sync.Mutex
+var cycleRunning bool
+
 func init() {
 	//Switch = new(bool)
 	//*Switch = true
@@ -27,6 +46,37 @@ func Run() (err error) {
 	statusSwitchOn = make(chan bool)
 	statusSwitchOff = make(chan bool)
 	//routineSwitch = make(chan bool)
+	startSpool()
+	collect.AnnotationAllowList = splitList(RunFlag.ServerAnnotations)
+	collect.UseProtobuf = RunFlag.ServerProtobuf == "on"
+	collect.ClusterName = RunFlag.ServerCluster
+	if RunFlag.ServerGeneric != "" {
+		if err := collect.LoadGenericResources(RunFlag.ServerGeneric); err != nil {
+			return err
+		}
+	}
+	if err := collect.Configure(RunFlag.ServerCollectors); err != nil {
+		return err
+	}
+	switch {
+	case RunFlag.ServerReplay != "":
+		if err := collect.Replay(RunFlag.ServerReplay); err != nil {
+			return err
+		}
+	case RunFlag.ServerRecord != "":
+		if err := collect.Record(RunFlag.ServerRecord); err != nil {
+			return err
+		}
+	}
+	if err := collect.Init(kubeMaster()); err != nil {
+		common.LogErr(err)
+	}
+	if err := startElection(); err != nil {
+		return err
+	}
+	if err := startShard(); err != nil {
+		return err
+	}
 	collectMainInOnCycle()
 	return nil
 }
@@ -35,15 +85,152 @@ func TurnStatus(status bool) {
 	switch status {
 	case true:
 		common.DebugPrint("turn the SwitchOn")
-		statusSwitchOn <- true
+		select {
+		case statusSwitchOn <- true:
+		case <-stopCollect:
+		}
 	case false:
 		common.DebugPrint("turn the SwitchOff")
-		statusSwitchOff <- false
+		select {
+		case statusSwitchOff <- false:
+		case <-stopCollect:
+		}
+	}
+}
+
+// Shutdown stops the collect loop and waits for the cycle in flight until ctx
+// is done; a cycle still running then is aborted and its run marked
+// interrupted. The election and the shard are left, and the spool and a
+// cassette being recorded are flushed, once the cycles drained.
+func Shutdown(ctx context.Context) error {
+	cycleLock.Lock()
+	stopOnce.Do(func() { close(stopCollect) })
+	cycleLock.Unlock()
+	drained := make(chan struct{})
+	go func() {
+		cycles.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		common.DebugPrint("the cycle in flight did not finish in time, aborting it")
+		collect.Abort()
+		select {
+		case <-drained:
+		case <-time.After(5 * time.Second):
+			common.DebugPrint("the aborted cycle did not finish, its run stays running")
+		}
+	}
+	close(stopBackground)
+	background.Wait()
+	var err error
+	if dao.DefaultSpool != nil {
+		err = dao.DefaultSpool.Close()
+	}
+	if cerr := collect.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ShutdownTimeout is how long Shutdown waits for the cycle in flight.
+func ShutdownTimeout() time.Duration {
+	seconds, err := strconv.Atoi(RunFlag.ServerShutdownTimeout)
+	if err != nil || seconds <= 0 {
+		seconds = 30
 	}
+	return time.Duration(seconds) * time.Second
+}
+
+// goBackground runs fn, which returns once stop is closed, so Shutdown can
+// wait for it.
+func goBackground(fn func(stop <-chan struct{})) {
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		fn(stopBackground)
+	}()
+}
+
+func startSpool() {
+	if RunFlag.ServerSpoolDir == "off" {
+		return
+	}
+	maxMB, err := strconv.ParseInt(RunFlag.ServerSpoolMax, 10, 64)
+	if err != nil || maxMB <= 0 {
+		maxMB = 256
+	}
+	if err := dao.InitSpool(RunFlag.ServerSpoolDir, maxMB<<20); err != nil {
+		common.LogErr(err)
+		return
+	}
+	common.DebugPrint("spool is open at", RunFlag.ServerSpoolDir, "pending", dao.DefaultSpool.Pending())
+	goBackground(func(stop <-chan struct{}) { dao.DefaultSpool.Run(5*time.Second, stop) })
+}
+
+// kubeMaster builds the apiserver URL from the kubeip and kubeport settings,
+// empty when kubeip is not set.
+func kubeMaster() string {
+	if RunFlag.ServerKubeIp == "" {
+		return ""
+	}
+	if strings.Contains(RunFlag.ServerKubeIp, "://") {
+		return RunFlag.ServerKubeIp
+	}
+	port := RunFlag.ServerKubePort
+	if port == "" {
+		port = "8080"
+	}
+	return "http://" + RunFlag.ServerKubeIp + ":" + port
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// Collecting reports whether the collect loop is switched on.
+func Collecting() bool {
+	return *statusSwitchLast
+}
+
+// startCycle starts a cycle unless the loop is stopping or the previous cycle
+// is still running.
+func startCycle() {
+	cycleLock.Lock()
+	defer cycleLock.Unlock()
+	select {
+	case <-stopCollect:
+		return
+	default:
+	}
+	if cycleRunning {
+		common.DebugPrint("the previous cycle is still running, skipping the tick")
+		return
+	}
+	cycleRunning = true
+	cycles.Add(1)
+	go runOneCycle()
 }
 
 func runOneCycle() {
+	defer func() {
+		cycleLock.Lock()
+		cycleRunning = false
+		cycleLock.Unlock()
+		cycles.Done()
+	}()
 	//routineSwitch <- *Switch
+	if !Leading() {
+		common.DebugPrint("not the leader, skipping the cycle")
+		return
+	}
 	collect.RunOneCycle()
 }
 func getState() (s bool) {
@@ -70,16 +257,24 @@ func turnState() {
 */
 func collectMainInOnCycle() {
 	//var i = 0
-	ticker := time.NewTicker(time.Millisecond * 5000)
+	ticker := time.NewTicker(loopInterval)
+	defer ticker.Stop()
 	common.DebugPrint("main routine is run")
-	for range ticker.C {
+	for {
+		select {
+		case <-stopCollect:
+			common.DebugPrint("main routine is stopped")
+			return
+		case <-ticker.C:
+		}
+		loopBeat()
 		ThreadCount.Add(1)
 		common.DebugPrint("run with the state", "statusSwitchLast is", *statusSwitchLast)
 		select {
 		case i := <-statusSwitchOn:
 			if i != *statusSwitchLast {
 				common.DebugPrint("into the select thread in statusSwitchOn")
-				go runOneCycle()
+				startCycle()
 				*statusSwitchLast = true
 				ThreadCount.Done()
 			}
@@ -93,7 +288,7 @@ func collectMainInOnCycle() {
 			switch *statusSwitchLast {
 			case true:
 				common.DebugPrint("into the select thread in default on")
-				go runOneCycle()
+				startCycle()
 				ThreadCount.Done()
 			case false:
 				common.DebugPrint("into the select thread in default off")
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestStartCycle(t *testing.T) {
+	defer func(stop chan struct{}) { stopCollect = stop }(stopCollect)
+
+	cycleRunning = true
+	startCycle()
+	cycleRunning = false
+	stopCollect = make(chan struct{})
+	close(stopCollect)
+	startCycle()
+
+	// neither call may have started a cycle: one was in flight, then the
+	// loop was stopping
+	if cycleRunning {
+		t.Error("a cycle was started")
+	}
+	cycles.Wait()
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"common"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"service/collect"
+	"service/query"
+	"strings"
+	"time"
+)
+
+// RunCommand runs the command line tool named by args[0] instead of the
+// collector and returns the process exit code.
+func RunCommand(args []string) int {
+	switch args[0] {
+	case "diff":
+		return diffCommand(args[1:])
+	case "import":
+		return importCommand(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+	return 2
+}
+
+func diffCommand(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	from := fs.String("from", "", "run id, tag or time of the first run")
+	to := fs.String("to", "", "run id, tag or time of the second run")
+	kind := fs.String("kind", "", "comma separated kinds to compare, all kinds when empty")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *from == "" || *to == "" {
+		fmt.Fprintln(os.Stderr, "diff: -from and -to are required")
+		return 2
+	}
+	var kinds []string
+	if *kind != "" {
+		kinds = strings.Split(*kind, ",")
+	}
+	diff, err := query.Diff(*from, *to, kinds)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "diff:", err)
+		return 1
+	}
+	return printJson(diff)
+}
+
+func printJson(v interface{}) int {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(string(out))
+	return 0
+}
+
+// importCommand stores kubectl List dumps, or tarballs of them, as collection
+// runs, one run per file given, dated with the file's modification time.
+func importCommand(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	cluster := fs.String("cluster", RunFlag.ServerCluster, "name of the cluster the dump was taken from")
+	taken := fs.String("time", "", "time the dump was taken, the file's modification time when empty")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || *cluster == "" {
+		fmt.Fprintln(os.Stderr, "usage: import -cluster name [-time time] file.json|dump.tar.gz ...")
+		return 2
+	}
+	var at time.Time
+	if *taken != "" {
+		t, err := common.ParseRecordTime(*taken)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "import: -time:", err)
+			return 2
+		}
+		at = t
+	}
+	if RunFlag.ServerGeneric != "" {
+		if err := collect.LoadGenericResources(RunFlag.ServerGeneric); err != nil {
+			fmt.Fprintln(os.Stderr, "import:", err)
+			return 1
+		}
+	}
+	code := 0
+	for _, file := range fs.Args() {
+		im := collect.NewImporter(*cluster)
+		err := importFile(im, file, at)
+		run, results := im.Finish()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "import:", err)
+			code = 1
+		}
+		if run == nil {
+			continue
+		}
+		for kind, n := range im.Skipped {
+			fmt.Fprintf(os.Stderr, "import: %s: skipped %d items of kind %q\n", file, n, kind)
+		}
+		if printJson(struct {
+			File    string      `json:"file"`
+			Run     interface{} `json:"run"`
+			Results interface{} `json:"results"`
+		}{file, run, results}) != 0 {
+			code = 1
+		}
+	}
+	return code
+}
+
+func importFile(im *collect.Importer, file string, at time.Time) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if at.IsZero() {
+		at = info.ModTime()
+	}
+	switch {
+	case strings.HasSuffix(file, ".tar.gz"), strings.HasSuffix(file, ".tgz"):
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		return importTar(im, tar.NewReader(zr), file, at)
+	case strings.HasSuffix(file, ".tar"):
+		return importTar(im, tar.NewReader(f), file, at)
+	}
+	return im.Read(f, file, at)
+}
+
+func importTar(im *collect.Importer, tr *tar.Reader, file string, at time.Time) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(hdr.Name, ".json") {
+			continue
+		}
+		if err := im.Read(tr, file+":"+hdr.Name, at); err != nil {
+			return err
+		}
+	}
+}
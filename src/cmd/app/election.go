@@ -0,0 +1,69 @@
+package app
+
+import (
+	"common"
+	"fmt"
+	"os"
+	"service/collect"
+	"service/election"
+	"strings"
+
+	"github.com/astaxie/beego/orm"
+)
+
+var elector *election.Elector
+
+// startElection starts competing for leadership when an election lock is
+// configured; without one this replica always collects.
+func startElection() error {
+	kind := RunFlag.ServerElection
+	if kind == "" || kind == "off" {
+		return nil
+	}
+	identity := replicaIdentity()
+	var lock election.Lock
+	switch kind {
+	case "mysql":
+		db, err := orm.GetDB("default")
+		if err != nil {
+			return err
+		}
+		lock = election.NewMysqlLock(db, RunFlag.ServerElectionLock)
+	case "endpoints", "configmaps":
+		parts := strings.SplitN(RunFlag.ServerElectionLock, "/", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("election lock %q is not namespace/name", RunFlag.ServerElectionLock)
+		}
+		lock = election.NewAnnotationLock(collect.KuberMasterIp, kind, parts[0], parts[1])
+	default:
+		return fmt.Errorf("unknown election lock %q", kind)
+	}
+	elector = election.NewElector(lock, identity)
+	// a cycle begun as the leader stops, the new leader collects
+	elector.OnStepDown = collect.Interrupt
+	collect.Leading = elector.Leader
+	common.DebugPrint("election: competing for", lock.Describe(), "as", identity)
+	goBackground(elector.Run)
+	return nil
+}
+
+// replicaIdentity names this replica, its host and pid unless configured.
+func replicaIdentity() string {
+	if RunFlag.ServerIdentity != "" {
+		return RunFlag.ServerIdentity
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Leading reports whether this replica leads and so collects.
+func Leading() bool {
+	return elector == nil || elector.Leader()
+}
+
+func ElectionStatus() election.Status {
+	if elector == nil {
+		return election.Status{Leader: true, Recent: []election.Transition{}}
+	}
+	return elector.Status()
+}
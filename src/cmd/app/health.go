@@ -0,0 +1,82 @@
+package app
+
+import (
+	"dao"
+	"fmt"
+	"service/collect"
+	"service/health"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// loopInterval is the period of the collect loop.
+const loopInterval = 5 * time.Second
+
+var (
+	beatLock sync.Mutex
+	lastBeat = time.Now()
+)
+
+func loopBeat() {
+	beatLock.Lock()
+	defer beatLock.Unlock()
+	lastBeat = time.Now()
+}
+
+// wedgedAfter is how long the loop or a cycle may go without progress before
+// /healthz fails and the collector gets restarted.
+func wedgedAfter() time.Duration {
+	n, err := strconv.Atoi(RunFlag.ServerLivenessIntervals)
+	if err != nil || n <= 0 {
+		n = 24
+	}
+	return time.Duration(n) * loopInterval
+}
+
+// Liveness checks that the collect loop and the cycles are not stuck. It
+// does not look at the apiserver or the database, restarting would not fix
+// those.
+func Liveness() []health.Check {
+	return []health.Check{
+		{Name: "loop", Run: func() (string, error) {
+			beatLock.Lock()
+			since := time.Since(lastBeat)
+			beatLock.Unlock()
+			if since > wedgedAfter() {
+				return "", fmt.Errorf("the collect loop last ran %s ago", since.Truncate(time.Second))
+			}
+			return "", nil
+		}},
+		{Name: "cycle", Run: func() (string, error) {
+			return "", collect.Stalled(wedgedAfter())
+		}},
+	}
+}
+
+// Readiness checks that a cycle can collect: the apiserver answers, the
+// database takes writes and its tables are as new as this build.
+func Readiness() []health.Check {
+	return []health.Check{
+		{Name: "apiserver", Run: collect.Ping},
+		{Name: "store", Run: func() (string, error) {
+			if err := dao.Db_writable(); err != nil {
+				return "", err
+			}
+			if dao.DefaultSpool != nil && dao.DefaultSpool.Pending() > 0 {
+				return fmt.Sprintf("%d rows spooled", dao.DefaultSpool.Pending()), nil
+			}
+			return "", nil
+		}},
+		{Name: "schema", Run: func() (string, error) {
+			version, err := dao.Db_schemaVersion()
+			if err != nil {
+				return "", err
+			}
+			if version < dao.SchemaVersion {
+				return "", fmt.Errorf("the database is at schema version %d, this build needs %d", version, dao.SchemaVersion)
+			}
+			return "schema version " + strconv.Itoa(version), nil
+		}},
+	}
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"common"
+	"fmt"
+	"service/collect"
+	"service/shard"
+)
+
+var collectShard *shard.Shard
+
+// startShard makes this replica collect only its share of the namespaces
+// when sharding is on.
+func startShard() error {
+	if RunFlag.ServerShard == "" || RunFlag.ServerShard == "off" {
+		return nil
+	}
+	if RunFlag.ServerElection != "" && RunFlag.ServerElection != "off" {
+		return fmt.Errorf("sharding and leader election exclude each other")
+	}
+	collectShard = shard.New(replicaIdentity(), shard.DbMembers{})
+	collect.CurrentShard = collectShard
+	common.DebugPrint("shard: collecting a share of the namespaces as", collectShard.Identity)
+	goBackground(collectShard.Run)
+	return nil
+}
+
+// ShardStatus is nil when the collection is not sharded.
+func ShardStatus() *shard.Status {
+	if collectShard == nil {
+		return nil
+	}
+	s := collectShard.Status()
+	return &s
+}
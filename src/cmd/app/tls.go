@@ -0,0 +1,34 @@
+package app
+
+import (
+	"common"
+	"crypto/tls"
+	"errors"
+	"service/certs"
+)
+
+// ServerTLS is the TLS config of the REST API, nil to serve plain HTTP. With
+// a client CA but auth off a client certificate is the only thing keeping
+// callers out, so it is required; with auth on, callers without one may
+// still present a token.
+func ServerTLS() (*tls.Config, error) {
+	if RunFlag.ServerTLSCert == "" && RunFlag.ServerTLSKey == "" {
+		if RunFlag.ServerTLSClientCA != "" {
+			return nil, errors.New("tlsclientca needs tlscert and tlskey")
+		}
+		return nil, nil
+	}
+	if RunFlag.ServerTLSCert == "" || RunFlag.ServerTLSKey == "" {
+		return nil, errors.New("tlscert and tlskey go together")
+	}
+	clientAuth := tls.VerifyClientCertIfGiven
+	if RunFlag.ServerAuth == "" || RunFlag.ServerAuth == "off" {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+	r, err := certs.New(RunFlag.ServerTLSCert, RunFlag.ServerTLSKey, RunFlag.ServerTLSClientCA, clientAuth)
+	if err != nil {
+		return nil, err
+	}
+	common.DebugPrint("serving the REST API over TLS with", RunFlag.ServerTLSCert, "client CA", RunFlag.ServerTLSClientCA)
+	return r.TLSConfig(), nil
+}
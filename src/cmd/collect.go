@@ -10,18 +10,61 @@ import (
 	"log"
 	"net/http"
 	"control"
+	"service/audit"
 	"common"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"context"
 )
 
 func main() {
+	if flag.NArg() > 0 {
+		os.Exit(app.RunCommand(flag.Args()))
+	}
 	if common.Debug0 == true {
 		common.DebugPrint("The cpu core is", runtime.NumCPU(), ",The app would use all of cores")
 	}
 	runtime.GOMAXPROCS(runtime.NumCPU())
 	rand.Seed(time.Now().UTC().UnixNano())
-	go app.Run()
-	log.Fatal(http.ListenAndServe(":8080", func() http.Handler {
-		s, _ := control.CollectRouters()
-		return s
-	}()))
+	go func() {
+		// a bad configuration must not leave the API serving without a
+		// collector behind it
+		if err := app.Run(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	router, err := control.CollectRouters()
+	if err != nil {
+		log.Fatal(err)
+	}
+	tlsConfig, err := app.ServerTLS()
+	if err != nil {
+		log.Fatal(err)
+	}
+	server := &http.Server{Addr: ":8080", Handler: router, TLSConfig: tlsConfig}
+	go func() {
+		var err error
+		if tlsConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	common.DebugPrint("stopping on", <-stop)
+	ctx, cancel := context.WithTimeout(context.Background(), app.ShutdownTimeout())
+	defer cancel()
+	common.LogErr(app.Shutdown(ctx))
+	// the status endpoints stay up while the cycle drains
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	common.LogErr(server.Shutdown(ctx))
+	common.LogErr(audit.Close())
 }
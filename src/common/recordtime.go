@@ -0,0 +1,35 @@
+package common
+
+import (
+	"strconv"
+	"time"
+)
+
+// TimeLayout is the layout of every time column written by the collector.
+// Record times are kept in UTC+8, the zone the database is read in.
+const TimeLayout = "2006-01-02 15:04:05"
+
+var recordZone = time.FixedZone("UTC+8", 8*60*60)
+
+func RecordTime(t time.Time) string {
+	return t.In(recordZone).Format(TimeLayout)
+}
+
+func RecordNow() string {
+	return RecordTime(time.Now())
+}
+
+// ParseRecordTime accepts a record time, an RFC 3339 time or unix seconds.
+func ParseRecordTime(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation(TimeLayout, s, recordZone); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
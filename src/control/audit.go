@@ -0,0 +1,35 @@
+package control
+
+import (
+	"errors"
+	"net/http"
+	"service/auth"
+	"service/query"
+	"strconv"
+)
+
+func init() {
+	routerMap["getAudit"] = Router{Path: "/audit", HandlerFunc: getAudit, Method: "GET", Role: auth.Admin}
+}
+
+func getAudit(w http.ResponseWriter, r *http.Request) {
+	window, ok := getWindow(w, r)
+	if !ok {
+		return
+	}
+	limit := 100
+	if s := r.FormValue("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > 1000 {
+			responseError(w, http.StatusBadRequest, errors.New("limit is a number from 1 to 1000"))
+			return
+		}
+		limit = n
+	}
+	rows, err := query.AuditLog(window, r.FormValue("caller"), limit)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"window": window, "calls": rows})
+}
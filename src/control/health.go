@@ -0,0 +1,32 @@
+package control
+
+import (
+	"cmd/app"
+	"net/http"
+	"service/health"
+	"time"
+)
+
+// probeTimeout keeps a probe answering before the kubelet gives up on it.
+const probeTimeout = 8 * time.Second
+
+func init() {
+	routerMap["getHealthz"] = Router{Path: "/healthz", HandlerFunc: getHealthz, Method: "GET", Public: true}
+	routerMap["getReadyz"] = Router{Path: "/readyz", HandlerFunc: getReadyz, Method: "GET", Public: true}
+}
+
+func getHealthz(w http.ResponseWriter, r *http.Request) {
+	responseReport(w, health.Run(app.Liveness(), probeTimeout))
+}
+
+func getReadyz(w http.ResponseWriter, r *http.Request) {
+	responseReport(w, health.Run(app.Readiness(), probeTimeout))
+}
+
+func responseReport(w http.ResponseWriter, report health.Report) {
+	code := http.StatusOK
+	if !report.Ok() {
+		code = http.StatusServiceUnavailable
+	}
+	responseJson(w, code, report)
+}
@@ -0,0 +1,252 @@
+package control
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"service/query"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	routerMap["getInventory"] = Router{Path: "/inventory/{kind}", HandlerFunc: getInventory, Method: "GET"}
+	routerMap["getChurn"] = Router{Path: "/report/churn", HandlerFunc: getChurn, Method: "GET"}
+	routerMap["getPodLifetime"] = Router{Path: "/report/lifetime", HandlerFunc: getPodLifetime, Method: "GET"}
+	routerMap["getDiff"] = Router{Path: "/diff", HandlerFunc: getDiff, Method: "GET"}
+	routerMap["getQuotaUsage"] = Router{Path: "/report/quota", HandlerFunc: getQuotaUsage, Method: "GET"}
+	routerMap["getStorage"] = Router{Path: "/report/storage", HandlerFunc: getStorage, Method: "GET"}
+	routerMap["getPods"] = Router{Path: "/pods", HandlerFunc: getPods, Method: "GET"}
+	routerMap["getUnreadyServices"] = Router{Path: "/report/services/unready", HandlerFunc: getUnreadyServices, Method: "GET"}
+	routerMap["getNodeVersions"] = Router{Path: "/report/nodes/versions", HandlerFunc: getNodeVersions, Method: "GET"}
+	routerMap["getRun"] = Router{Path: "/runs/{run}", HandlerFunc: getRun, Method: "GET"}
+	routerMap["getWorkloads"] = Router{Path: "/workloads", HandlerFunc: getWorkloads, Method: "GET"}
+	routerMap["getStuckRollouts"] = Router{Path: "/report/rollouts/stuck", HandlerFunc: getStuckRollouts, Method: "GET"}
+	routerMap["getGeneric"] = Router{Path: "/generic/{kind}", HandlerFunc: getGeneric, Method: "GET"}
+	routerMap["getObjects"] = Router{Path: "/objects/{kind}", HandlerFunc: getObjects, Method: "GET"}
+	routerMap["getNodeConditions"] = Router{Path: "/report/nodes/conditions", HandlerFunc: getNodeConditions, Method: "GET"}
+}
+
+func responseError(w http.ResponseWriter, code int, err error) {
+	responseJson(w, code, map[string]string{"error": err.Error()})
+}
+
+func queryError(w http.ResponseWriter, err error) {
+	if _, ok := err.(query.NotFoundError); ok {
+		responseError(w, http.StatusNotFound, err)
+		return
+	}
+	if _, ok := err.(query.SelectorError); ok {
+		responseError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, ok := err.(query.IncompleteError); ok {
+		responseError(w, http.StatusConflict, err)
+		return
+	}
+	responseError(w, http.StatusInternalServerError, err)
+}
+
+func getWindow(w http.ResponseWriter, r *http.Request) (query.Window, bool) {
+	window, err := query.NewWindow(r.FormValue("from"), r.FormValue("to"))
+	if err != nil {
+		responseError(w, http.StatusBadRequest, err)
+		return window, false
+	}
+	return window, true
+}
+
+func getInventory(w http.ResponseWriter, r *http.Request) {
+	rows, err := query.Inventory(mux.Vars(r)["kind"], r.FormValue("state"), r.FormValue("namespace"))
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, rows)
+}
+
+func getChurn(w http.ResponseWriter, r *http.Request) {
+	window, ok := getWindow(w, r)
+	if !ok {
+		return
+	}
+	kind := r.FormValue("kind")
+	if kind == "" {
+		kind = "pods"
+	}
+	rows, err := query.Churn(kind, window)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"kind": kind, "window": window, "namespaces": rows})
+}
+
+func getPodLifetime(w http.ResponseWriter, r *http.Request) {
+	window, ok := getWindow(w, r)
+	if !ok {
+		return
+	}
+	rows, err := query.PodLifetime(window, r.FormValue("namespace"))
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"window": window, "namespaces": rows})
+}
+
+func getDiff(w http.ResponseWriter, r *http.Request) {
+	from, to := r.FormValue("from"), r.FormValue("to")
+	if from == "" || to == "" {
+		responseJson(w, http.StatusBadRequest, map[string]string{"error": "from and to are required"})
+		return
+	}
+	var kinds []string
+	if kind := r.FormValue("kind"); kind != "" {
+		kinds = strings.Split(kind, ",")
+	}
+	diff, err := query.Diff(from, to, kinds)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, diff)
+}
+
+func getQuotaUsage(w http.ResponseWriter, r *http.Request) {
+	window, ok := getWindow(w, r)
+	if !ok {
+		return
+	}
+	rows, err := query.QuotaUsage(r.FormValue("namespace"), r.FormValue("resource"), window)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"window": window, "usage": rows})
+}
+
+func getStorage(w http.ResponseWriter, r *http.Request) {
+	report, err := query.Storage(r.FormValue("run"))
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, report)
+}
+
+func getNodeVersions(w http.ResponseWriter, r *http.Request) {
+	report, err := query.NodeVersions(r.FormValue("run"))
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, report)
+}
+
+func getNodeConditions(w http.ResponseWriter, r *http.Request) {
+	window, ok := getWindow(w, r)
+	if !ok {
+		return
+	}
+	periods, err := query.NodeConditionHistory(r.FormValue("node"), r.FormValue("type"), window)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"window": window, "periods": periods})
+}
+
+func getPods(w http.ResponseWriter, r *http.Request) {
+	filters := make(map[string]string)
+	for _, name := range []string{"namespace", "node", "phase", "qos", "owner", "ownerkind"} {
+		filters[name] = r.FormValue(name)
+	}
+	run, pods, err := query.Pods(r.FormValue("run"), filters, r.FormValue("selector"))
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"run": run, "pods": pods})
+}
+
+func getUnreadyServices(w http.ResponseWriter, r *http.Request) {
+	run, services, err := query.UnreadyServices(r.FormValue("run"))
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"run": run, "services": services})
+}
+
+func getObjects(w http.ResponseWriter, r *http.Request) {
+	kind := mux.Vars(r)["kind"]
+	run, objects, err := query.Objects(kind, r.FormValue("run"), r.FormValue("selector"), r.FormValue("namespace"))
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"run": run, "kind": kind, "objects": objects})
+}
+
+func getRun(w http.ResponseWriter, r *http.Request) {
+	ref := mux.Vars(r)["run"]
+	if ref == "latest" {
+		ref = ""
+	}
+	run, results, err := query.RunDetail(ref)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"run": run, "collectors": results})
+}
+
+func getWorkloads(w http.ResponseWriter, r *http.Request) {
+	run, workloads, err := query.Workloads(r.FormValue("run"), r.FormValue("kind"), r.FormValue("namespace"))
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"run": run, "workloads": workloads})
+}
+
+// getStuckRollouts reports workloads that have not converged for the number
+// of minutes given by after, 10 by default.
+func getStuckRollouts(w http.ResponseWriter, r *http.Request) {
+	after := 10 * time.Minute
+	if v := r.FormValue("after"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes < 0 {
+			responseError(w, http.StatusBadRequest, fmt.Errorf("invalid after %q, want minutes", v))
+			return
+		}
+		after = time.Duration(minutes) * time.Minute
+	}
+	run, stuck, err := query.StuckRollouts(r.FormValue("run"), after)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"run": run, "after_minutes": int(after / time.Minute), "workloads": stuck})
+}
+
+// getGeneric lists generically collected objects; field.<name>=<value>
+// parameters narrow them down by their extracted fields.
+func getGeneric(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	match := make(map[string]string)
+	for key, values := range r.Form {
+		if strings.HasPrefix(key, "field.") && len(values) > 0 {
+			match[strings.TrimPrefix(key, "field.")] = values[0]
+		}
+	}
+	kind := mux.Vars(r)["kind"]
+	run, objects, err := query.GenericObjects(kind, r.FormValue("run"), r.FormValue("namespace"), match)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+	responseJson(w, http.StatusOK, map[string]interface{}{"run": run, "kind": kind, "objects": objects})
+}
@@ -7,30 +7,50 @@ import (
 	"cmd/app"
 	"common"
 	"errors"
+	"service/audit"
+	"service/auth"
 )
 
 type Router struct {
 	Path        string
 	HandlerFunc http.HandlerFunc
 	Method      string
+	// Role is the least role allowed to call the route once auth is on,
+	// unless the route is Public.
+	Role   auth.Role
+	Public bool
 }
 
-var routerMap map[string]Router
+var routerMap = make(map[string]Router)
 
 func init() {
-	routerMap = make(map[string]Router)
-	routerMap["getStatus"] = Router{Path: "/{status}", HandlerFunc: getStatus, Method: "POST"}
-	routerMap["getStatusIndex"] = Router{Path: "/{status}", HandlerFunc: getStatusIndex, Method: "GET"}
+	routerMap["getStatus"] = Router{Path: "/status/{status}", HandlerFunc: getStatus, Method: "POST", Role: auth.Operator}
+	routerMap["getStatusIndex"] = Router{Path: "/status/{status}", HandlerFunc: getStatusIndex, Method: "GET"}
 }
 
 func CollectRouters() (router *mux.Router, err error) {
-	router = mux.NewRouter().StrictSlash(true).PathPrefix("/status").Subrouter() /*StrictSlash: /path/ to /path */
+	router = mux.NewRouter().StrictSlash(true) /*StrictSlash: /path/ to /path */
 	if router == nil {
 		return nil, err
 	}
+	authenticator, err := loadAuth()
+	if err != nil {
+		return nil, err
+	}
+	if path := app.RunFlag.ServerAuditFile; path != "" && path != "off" {
+		if err := audit.Open(path); err != nil {
+			return nil, err
+		}
+	}
 	for key, v := range routerMap {
-		var handler http.Handler
-		handler = common.HttpLog(v.HandlerFunc, key)
+		var handler http.Handler = v.HandlerFunc
+		if authenticator != nil && !v.Public {
+			handler = authenticator.Require(v.Role, key, handler)
+		}
+		if v.Method != "GET" {
+			handler = audit.Middleware(key, handler)
+		}
+		handler = common.HttpLog(handler, key)
 		router.Methods(v.Method).Path(v.Path).Name(key).Handler(handler)
 		if handler == nil {
 			err = errors.New("func is wrong" + key)
@@ -43,6 +63,20 @@ func CollectRouters() (router *mux.Router, err error) {
 	return router, nil
 }
 
+// loadAuth reads the auth config file, nil when auth is off.
+func loadAuth() (*auth.Authenticator, error) {
+	path := app.RunFlag.ServerAuth
+	if path == "" || path == "off" {
+		return nil, nil
+	}
+	a, err := auth.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	common.DebugPrint("the REST API needs a bearer token from", path)
+	return a, nil
+}
+
 func responseCode200(w http.ResponseWriter, r *http.Request, bodyString string) {
 	w.Header().Set("Content-Type", "application/json;   charset=UTF-8")
 	w.WriteHeader(http.StatusOK)
@@ -71,7 +105,5 @@ func getStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func getStatusIndex(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json;   charset=UTF-8")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintln(w, "post the app status")
+	responseJson(w, http.StatusOK, getAppStatus())
 }
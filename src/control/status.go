@@ -0,0 +1,49 @@
+package control
+
+import (
+	"cmd/app"
+	"dao"
+	"encoding/json"
+	model "model/collect"
+	"net/http"
+	"service/collect"
+	"service/election"
+	"service/shard"
+)
+
+type appStatus struct {
+	Collect bool            `json:"collect"`
+	Spool   dao.SpoolStatus `json:"spool"`
+	// Collectors holds the per kind results of the latest cycle.
+	Collectors []model.RunResults `json:"collectors"`
+	// Discovery is nil until the apiserver answered discovery once.
+	Discovery *collect.Discovery `json:"discovery"`
+	// Election shows whether this replica leads, and so collects.
+	Election election.Status `json:"election"`
+	// Shard is the share of the namespaces this replica collects, nil when
+	// the collection is not sharded.
+	Shard *shard.Status `json:"shard"`
+}
+
+func getAppStatus() appStatus {
+	return appStatus{
+		Collect:    app.Collecting(),
+		Spool:      dao.GetSpoolStatus(),
+		Collectors: collect.LastResults(),
+		Discovery:  collect.CurrentDiscovery(),
+		Election:   app.ElectionStatus(),
+		Shard:      app.ShardStatus(),
+	}
+}
+
+func responseJson(w http.ResponseWriter, code int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		code = http.StatusInternalServerError
+		body, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	w.Header().Set("Content-Type", "application/json;   charset=UTF-8")
+	w.WriteHeader(code)
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
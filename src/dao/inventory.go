@@ -0,0 +1,35 @@
+package dao
+
+import (
+	"github.com/astaxie/beego/orm"
+)
+
+// Db_upsertInventory records that the object uid was seen by the run tagged
+// tag at now. An object seen again after being marked deleted comes back to
+// life with its original First_seen.
+func Db_upsertInventory(table string, uid string, namespace string, name string, now string, tag string) error {
+	return DefaultStore.UpsertInventory(table, uid, namespace, name, now, tag)
+}
+
+// Db_markInventoryDeleted marks every live object that the run tagged tag did
+// not see as deleted at now. A run that only collected some namespaces passes
+// them, nil stands for all.
+func Db_markInventoryDeleted(table string, tag string, now string, namespaces []string) (int64, error) {
+	return DefaultStore.MarkInventoryDeleted(table, tag, now, namespaces)
+}
+
+// Db_markOrphanedInventoryDeleted marks every live object of a namespace not
+// in namespaces as deleted at now, unless it was seen since.
+func Db_markOrphanedInventoryDeleted(table string, now string, namespaces []string) (int64, error) {
+	return DefaultStore.MarkOrphanedInventoryDeleted(table, now, namespaces)
+}
+
+func Db_update(model interface{}, cols ...string) (int64, error) {
+	return DefaultStore.Update(model, cols...)
+}
+
+// Db_updateByTag sets columns on every row of table recorded with tag. Rows
+// still waiting in the spool are not updated.
+func Db_updateByTag(table string, tag string, values orm.Params) (int64, error) {
+	return DefaultStore.UpdateByTag(table, tag, values)
+}
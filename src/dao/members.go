@@ -0,0 +1,36 @@
+package dao
+
+import (
+	"github.com/astaxie/beego/orm"
+)
+
+// Db_heartbeat records that the replica identity is alive at now.
+func Db_heartbeat(identity string, now string) error {
+	_, err := orm.NewOrm().Raw("INSERT INTO `collector_members` (`identity`, `Started`, `Heartbeat`) VALUES (?, ?, ?)"+
+		" ON DUPLICATE KEY UPDATE `Heartbeat` = VALUES(`Heartbeat`)", identity, now, now).Exec()
+	return err
+}
+
+// Db_aliveMembers returns the replicas with a heartbeat at or after since.
+func Db_aliveMembers(since string) ([]string, error) {
+	var identities orm.ParamsList
+	_, err := orm.NewOrm().QueryTable("collector_members").Filter("Heartbeat__gte", since).
+		OrderBy("identity").ValuesFlat(&identities, "identity")
+	if err != nil {
+		return nil, err
+	}
+	members := make([]string, 0, len(identities))
+	for _, id := range identities {
+		if s, ok := id.(string); ok {
+			members = append(members, s)
+		}
+	}
+	return members, nil
+}
+
+// Db_leaveMembers removes the replica identity at once instead of letting its
+// heartbeat expire.
+func Db_leaveMembers(identity string) error {
+	_, err := orm.NewOrm().QueryTable("collector_members").Filter("identity", identity).Delete()
+	return err
+}
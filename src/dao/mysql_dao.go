@@ -1,13 +1,80 @@
 package dao
 
 import (
-	"github.com/astaxie/beego/orm"
+	"database/sql/driver"
+	"net"
 
+	"github.com/go-sql-driver/mysql"
 )
 
+var DefaultSpool *Spool
 
+// Db_insert writes model to the database. When the database is unavailable and
+// a spool is configured the model is queued on disk instead and ErrSpooled is
+// returned; queued models are replayed in order before new ones are written.
 func Db_insert(model interface{}) (int64, error) {
-	o := orm.NewOrm()
-	uid,err:=o.Insert(model)
-	return uid,err
-}
\ No newline at end of file
+	if DefaultSpool != nil && DefaultSpool.Pending() > 0 {
+		if DefaultSpool.Backoff() || DefaultSpool.Replay() != nil {
+			return 0, spoolInsert(model, nil)
+		}
+	}
+	uid, err := ormInsert(model)
+	if err != nil && DefaultSpool != nil && storeUnavailable(err) {
+		return 0, spoolInsert(model, err)
+	}
+	return uid, err
+}
+
+// Db_tryInsert writes model to the database at once, never to the spool, for
+// rows that are updated later: a spooled row cannot be.
+func Db_tryInsert(model interface{}) (int64, error) {
+	return ormInsert(model)
+}
+
+func ormInsert(model interface{}) (int64, error) {
+	return DefaultStore.Insert(model)
+}
+
+func spoolInsert(model interface{}, cause error) error {
+	if err := DefaultSpool.Append(model); err != nil {
+		if cause != nil {
+			return cause
+		}
+		return err
+	}
+	return ErrSpooled
+}
+
+// storeUnavailable tells errors worth retrying later apart from rows the
+// server rejected, which would fail again on replay.
+func storeUnavailable(err error) bool {
+	switch e := err.(type) {
+	case *mysql.MySQLError:
+		switch e.Number {
+		case 1040, 1053, 1205, 1213, 1290, 1836:
+			return true
+		}
+		return false
+	case net.Error:
+		return true
+	}
+	return err == driver.ErrBadConn || err == mysql.ErrInvalidConn
+}
+
+// InitSpool enables spooling of failed inserts under dir.
+func InitSpool(dir string, maxBytes int64) error {
+	s, err := OpenSpool(dir, maxBytes, ormInsert)
+	if err != nil {
+		return err
+	}
+	s.retryable = storeUnavailable
+	DefaultSpool = s
+	return nil
+}
+
+func GetSpoolStatus() SpoolStatus {
+	if DefaultSpool == nil {
+		return SpoolStatus{}
+	}
+	return DefaultSpool.Status()
+}
@@ -0,0 +1,39 @@
+package dao
+
+import (
+	"errors"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// SchemaVersion is the version of the tables and views this build writes and
+// reads. Raise it with every change to the models or views so a replica
+// finding an older database is not ready until the tables were synced.
+const SchemaVersion = 2
+
+// Db_setSchemaVersion records that the database was synced to version.
+func Db_setSchemaVersion(version int, now string) error {
+	_, err := orm.NewOrm().Raw("INSERT INTO `schema_versions` (`version`, `Applied`) VALUES (?, ?)"+
+		" ON DUPLICATE KEY UPDATE `Applied` = VALUES(`Applied`)", version, now).Exec()
+	return err
+}
+
+// Db_schemaVersion returns the newest version the database was synced to, 0
+// when it never was.
+func Db_schemaVersion() (int, error) {
+	var version int
+	err := orm.NewOrm().Raw("SELECT COALESCE(MAX(`version`), 0) FROM `schema_versions`").QueryRow(&version)
+	return version, err
+}
+
+// Db_writable fails unless the database answers and accepts writes.
+func Db_writable() error {
+	var readOnly int
+	if err := orm.NewOrm().Raw("SELECT @@global.read_only").QueryRow(&readOnly); err != nil {
+		return err
+	}
+	if readOnly != 0 {
+		return errors.New("the database is read only")
+	}
+	return nil
+}
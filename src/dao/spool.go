@@ -0,0 +1,539 @@
+package dao
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A spool segment is a sequence of frames:
+//
+//	[4 byte payload length][4 byte crc32 of length+stamp+payload][8 byte unix nano stamp][payload]
+//
+// A frame is never larger than a segment, a longer length is corruption.
+// The payload is a gob encoded spoolEntry. Segments are named by an increasing
+// sequence number and are replayed oldest first; a cursor file records how far
+// into the oldest segment the replay got so a restart does not insert twice.
+const (
+	spoolFrameHeader   = 16
+	spoolSegmentSuffix = ".seg"
+	spoolCursorFile    = "cursor"
+	spoolCursorEvery   = 64
+)
+
+var ErrSpooled = errors.New("dao: insert failed, record spooled for replay")
+var errSpoolCorrupt = errors.New("dao: spool frame checksum mismatch")
+var errSpoolFrameSize = errors.New("dao: spool frame longer than a segment")
+
+var spoolModels = make(map[string]reflect.Type)
+var spoolModelsLock sync.RWMutex
+
+// RegisterSpoolModel makes models replayable after a restart. Models passed to
+// Db_insert are registered on first use as well.
+func RegisterSpoolModel(models ...interface{}) {
+	spoolModelsLock.Lock()
+	defer spoolModelsLock.Unlock()
+	for _, m := range models {
+		t := reflect.Indirect(reflect.ValueOf(m)).Type()
+		spoolModels[t.Name()] = t
+	}
+}
+
+func spoolModelType(kind string) (reflect.Type, bool) {
+	spoolModelsLock.RLock()
+	defer spoolModelsLock.RUnlock()
+	t, ok := spoolModels[kind]
+	return t, ok
+}
+
+type spoolEntry struct {
+	Kind  string
+	Model []byte
+}
+
+type spoolSegment struct {
+	seq     uint64
+	path    string
+	size    int64
+	records int64
+	oldest  time.Time
+}
+
+type SpoolStatus struct {
+	Enabled    bool    `json:"enabled"`
+	Dir        string  `json:"dir"`
+	Segments   int     `json:"segments"`
+	Records    int64   `json:"records"`
+	Bytes      int64   `json:"bytes"`
+	OldestAge  float64 `json:"oldest_age_seconds"`
+	Dropped    int64   `json:"dropped"`
+	Replayed   int64   `json:"replayed"`
+	Last_error string  `json:"last_error"`
+}
+
+type Spool struct {
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+	insert       func(interface{}) (int64, error)
+	retryable    func(error) bool
+
+	// replaying serializes the replays, which let go of mu around each
+	// insert so appends are not held up by the store.
+	replaying sync.Mutex
+
+	mu        sync.Mutex
+	segments  []*spoolSegment
+	active    *os.File
+	offset    int64 // read offset into segments[0]
+	dropped   int64
+	replayed  int64
+	lastErr   string
+	retryAt   time.Time
+	backoff   time.Duration
+	sinceSave int
+}
+
+// OpenSpool opens (or creates) the spool in dir. maxBytes bounds the total size
+// on disk; once it is exceeded the oldest segment is dropped.
+func OpenSpool(dir string, maxBytes int64, insert func(interface{}) (int64, error)) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &Spool{
+		dir:          dir,
+		maxBytes:     maxBytes,
+		segmentBytes: maxBytes / 16,
+		insert:       insert,
+		retryable:    func(error) bool { return true },
+		backoff:      time.Second,
+	}
+	if s.segmentBytes < 1<<20 {
+		s.segmentBytes = 1 << 20
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*"+spoolSegmentSuffix))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), spoolSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, &spoolSegment{seq: seq, path: name})
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+	s.loadCursor()
+	for i, seg := range s.segments {
+		start := int64(0)
+		if i == 0 {
+			start = s.offset
+		}
+		good, err := s.scanSegment(seg, start)
+		if err != nil {
+			log.Printf("spool: segment %s is damaged after offset %d: %v", seg.path, good, err)
+			if i == len(s.segments)-1 {
+				// a torn write at the tail, cut it so appends keep the framing intact
+				os.Truncate(seg.path, good)
+				seg.size = good
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *Spool) loadCursor() {
+	s.offset = 0
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, spoolCursorFile))
+	if err != nil || len(s.segments) == 0 {
+		return
+	}
+	var seq uint64
+	var offset int64
+	if _, err := fmt.Sscanf(string(b), "%d %d", &seq, &offset); err != nil {
+		return
+	}
+	if s.segments[0].seq == seq {
+		s.offset = offset
+	}
+}
+
+func (s *Spool) saveCursor() {
+	s.sinceSave = 0
+	if len(s.segments) == 0 {
+		os.Remove(filepath.Join(s.dir, spoolCursorFile))
+		return
+	}
+	tmp := filepath.Join(s.dir, spoolCursorFile+".tmp")
+	body := fmt.Sprintf("%d %d\n", s.segments[0].seq, s.offset)
+	if err := ioutil.WriteFile(tmp, []byte(body), 0644); err != nil {
+		return
+	}
+	os.Rename(tmp, filepath.Join(s.dir, spoolCursorFile))
+}
+
+// scanSegment counts the records of seg from start and returns the offset of
+// the end of the last intact frame.
+func (s *Spool) scanSegment(seg *spoolSegment, start int64) (int64, error) {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	seg.size = st.Size()
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return start, err
+	}
+	r := bufio.NewReader(f)
+	pos := start
+	for {
+		stamp, payload, err := readSpoolFrame(r, s.segmentBytes)
+		if err == io.EOF {
+			return pos, nil
+		}
+		if err != nil {
+			return pos, err
+		}
+		if seg.records == 0 {
+			seg.oldest = stamp
+		}
+		seg.records++
+		pos += int64(spoolFrameHeader + len(payload))
+	}
+}
+
+// readSpoolFrame reads the next frame of r; frames are at most max bytes.
+func readSpoolFrame(r io.Reader, max int64) (time.Time, []byte, error) {
+	var head [spoolFrameHeader]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return time.Time{}, nil, err
+	}
+	n := binary.BigEndian.Uint32(head[0:4])
+	sum := binary.BigEndian.Uint32(head[4:8])
+	if int64(n) > max-spoolFrameHeader {
+		return time.Time{}, nil, errSpoolFrameSize
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return time.Time{}, nil, err
+	}
+	crc := crc32.NewIEEE()
+	crc.Write(head[0:4])
+	crc.Write(head[8:16])
+	crc.Write(payload)
+	if crc.Sum32() != sum {
+		return time.Time{}, nil, errSpoolCorrupt
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(head[8:16]))), payload, nil
+}
+
+func encodeSpoolFrame(stamp time.Time, payload []byte) []byte {
+	frame := make([]byte, spoolFrameHeader+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint64(frame[8:16], uint64(stamp.UnixNano()))
+	copy(frame[spoolFrameHeader:], payload)
+	crc := crc32.NewIEEE()
+	crc.Write(frame[0:4])
+	crc.Write(frame[8:])
+	binary.BigEndian.PutUint32(frame[4:8], crc.Sum32())
+	return frame
+}
+
+// Append writes model to the tail of the spool.
+func (s *Spool) Append(model interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(model))
+	RegisterSpoolModel(v.Interface())
+	var body bytes.Buffer
+	if err := gob.NewEncoder(&body).Encode(v.Interface()); err != nil {
+		return err
+	}
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(spoolEntry{Kind: v.Type().Name(), Model: body.Bytes()}); err != nil {
+		return err
+	}
+	now := time.Now()
+	frame := encodeSpoolFrame(now, payload.Bytes())
+	if int64(len(frame)) > s.segmentBytes {
+		return errSpoolFrameSize
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seg, err := s.tail(int64(len(frame)))
+	if err != nil {
+		return err
+	}
+	if _, err := s.active.Write(frame); err != nil {
+		return err
+	}
+	if seg.records == 0 {
+		seg.oldest = now
+	}
+	seg.records++
+	seg.size += int64(len(frame))
+	s.enforceLimit()
+	return nil
+}
+
+// tail returns the segment appends go to, rotating when it would outgrow the
+// segment size.
+func (s *Spool) tail(need int64) (*spoolSegment, error) {
+	var last *spoolSegment
+	if len(s.segments) > 0 {
+		last = s.segments[len(s.segments)-1]
+	}
+	if last != nil && s.active != nil && last.size+need <= s.segmentBytes {
+		return last, nil
+	}
+	if last != nil && s.active == nil && last.size+need <= s.segmentBytes {
+		f, err := os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		s.active = f
+		return last, nil
+	}
+	if s.active != nil {
+		s.active.Sync()
+		s.active.Close()
+		s.active = nil
+	}
+	seq := uint64(1)
+	if last != nil {
+		seq = last.seq + 1
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, spoolSegmentSuffix))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s.active = f
+	seg := &spoolSegment{seq: seq, path: path}
+	s.segments = append(s.segments, seg)
+	return seg, nil
+}
+
+func (s *Spool) enforceLimit() {
+	for len(s.segments) > 1 && s.bytes() > s.maxBytes {
+		seg := s.segments[0]
+		log.Printf("spool: over %d bytes, dropping %d records of %s", s.maxBytes, seg.records, seg.path)
+		s.dropped += seg.records
+		s.removeHead()
+	}
+}
+
+func (s *Spool) removeHead() {
+	os.Remove(s.segments[0].path)
+	s.segments = s.segments[1:]
+	s.offset = 0
+	s.saveCursor()
+}
+
+func (s *Spool) bytes() int64 {
+	var n int64
+	for i, seg := range s.segments {
+		n += seg.size
+		if i == 0 {
+			n -= s.offset
+		}
+	}
+	return n
+}
+
+// Pending returns the number of records waiting to be replayed.
+func (s *Spool) Pending() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending()
+}
+
+func (s *Spool) pending() int64 {
+	var n int64
+	for _, seg := range s.segments {
+		n += seg.records
+	}
+	return n
+}
+
+// Backoff reports whether the last replay failed recently enough that the
+// store should be assumed down.
+func (s *Spool) Backoff() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Before(s.retryAt)
+}
+
+// Replay inserts spooled records oldest first and stops at the first failure.
+func (s *Spool) Replay() error {
+	s.replaying.Lock()
+	defer s.replaying.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.segments) > 0 {
+		err := s.replayHead()
+		if err != nil {
+			s.lastErr = err.Error()
+			s.retryAt = time.Now().Add(s.backoff)
+			if s.backoff < time.Minute {
+				s.backoff *= 2
+			}
+			s.saveCursor()
+			return err
+		}
+	}
+	s.backoff = time.Second
+	s.retryAt = time.Time{}
+	s.lastErr = ""
+	return nil
+}
+
+// replayHead replays the oldest segment with mu held, except around each
+// insert.
+func (s *Spool) replayHead() error {
+	seg := s.segments[0]
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(s.offset, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	for seg.records > 0 {
+		stamp, payload, err := readSpoolFrame(r, s.segmentBytes)
+		if err != nil {
+			// the rest of the segment cannot be framed any more
+			log.Printf("spool: dropping %d unreadable records of %s: %v", seg.records, seg.path, err)
+			s.dropped += seg.records
+			seg.records = 0
+			break
+		}
+		seg.oldest = stamp
+		model, err := decodeSpoolEntry(payload)
+		if err != nil {
+			log.Printf("spool: dropping record of %s: %v", seg.path, err)
+			s.dropped++
+		} else {
+			s.mu.Unlock()
+			_, err = s.insert(model)
+			s.mu.Lock()
+			if len(s.segments) == 0 || s.segments[0] != seg {
+				// the segment was dropped over the size limit meanwhile
+				return nil
+			}
+			switch {
+			case err != nil && s.retryable(err):
+				return err
+			case err != nil:
+				log.Printf("spool: dropping record of %s rejected by the store: %v", seg.path, err)
+				s.dropped++
+			default:
+				s.replayed++
+			}
+		}
+		seg.records--
+		s.offset += int64(spoolFrameHeader + len(payload))
+		if s.sinceSave++; s.sinceSave >= spoolCursorEvery {
+			s.saveCursor()
+		}
+	}
+	if s.active != nil && len(s.segments) == 1 {
+		s.active.Close()
+		s.active = nil
+	}
+	s.removeHead()
+	return nil
+}
+
+func decodeSpoolEntry(payload []byte) (interface{}, error) {
+	var entry spoolEntry
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&entry); err != nil {
+		return nil, err
+	}
+	t, ok := spoolModelType(entry.Kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown spooled model %q", entry.Kind)
+	}
+	model := reflect.New(t)
+	if err := gob.NewDecoder(bytes.NewReader(entry.Model)).Decode(model.Interface()); err != nil {
+		return nil, err
+	}
+	return model.Interface(), nil
+}
+
+// Run replays the spool every interval until stop is closed.
+func (s *Spool) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if s.Pending() > 0 && !s.Backoff() {
+				if err := s.Replay(); err != nil {
+					log.Printf("spool: replay stopped, %d records pending: %v", s.Pending(), err)
+				}
+			}
+		}
+	}
+}
+
+// Close flushes the active segment and the replay cursor.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saveCursor()
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Sync()
+	s.active.Close()
+	s.active = nil
+	return err
+}
+
+func (s *Spool) Status() SpoolStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := SpoolStatus{
+		Enabled:    true,
+		Dir:        s.dir,
+		Segments:   len(s.segments),
+		Records:    s.pending(),
+		Bytes:      s.bytes(),
+		Dropped:    s.dropped,
+		Replayed:   s.replayed,
+		Last_error: s.lastErr,
+	}
+	for _, seg := range s.segments {
+		if seg.records > 0 {
+			st.OldestAge = time.Since(seg.oldest).Seconds()
+			break
+		}
+	}
+	return st
+}
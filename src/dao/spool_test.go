@@ -0,0 +1,159 @@
+package dao
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type spoolTestRow struct {
+	Id   int64
+	Name string
+}
+
+func TestSpoolReplayInOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	down := errors.New("store down")
+	var got []string
+	insert := func(m interface{}) (int64, error) {
+		if down != nil {
+			return 0, down
+		}
+		got = append(got, m.(*spoolTestRow).Name)
+		return int64(len(got)), nil
+	}
+	s, err := OpenSpool(dir, 1<<20, insert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if err := s.Append(&spoolTestRow{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Replay(); err != down {
+		t.Fatalf("replay with store down returned %v", err)
+	}
+	if st := s.Status(); st.Records != 3 || st.Segments != 1 {
+		t.Fatalf("unexpected status %+v", st)
+	}
+	s.Close()
+
+	// a restart must pick the records up again
+	s, err = OpenSpool(dir, 1<<20, insert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	down = nil
+	if err := s.Replay(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Fatalf("replayed %v", got)
+	}
+	if s.Pending() != 0 {
+		t.Fatalf("%d records still pending", s.Pending())
+	}
+}
+
+func TestSpoolTornTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := OpenSpool(dir, 1<<20, func(interface{}) (int64, error) { return 0, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Append(&spoolTestRow{Name: "a"})
+	s.Append(&spoolTestRow{Name: "b"})
+	s.Close()
+
+	segs, _ := filepath.Glob(filepath.Join(dir, "*"+spoolSegmentSuffix))
+	if len(segs) != 1 {
+		t.Fatalf("expected one segment, got %v", segs)
+	}
+	st, _ := os.Stat(segs[0])
+	os.Truncate(segs[0], st.Size()-3)
+
+	s, err = OpenSpool(dir, 1<<20, func(interface{}) (int64, error) { return 0, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Pending() != 1 {
+		t.Fatalf("expected the intact record only, got %d", s.Pending())
+	}
+	if err := s.Append(&spoolTestRow{Name: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Pending() != 2 {
+		t.Fatalf("expected 2 records, got %d", s.Pending())
+	}
+}
+
+func TestSpoolFrameLength(t *testing.T) {
+	frame := encodeSpoolFrame(time.Now(), []byte("payload"))
+	if _, payload, err := readSpoolFrame(bytes.NewReader(frame), 1<<20); err != nil || string(payload) != "payload" {
+		t.Fatalf("read %q, %v", payload, err)
+	}
+	huge := append([]byte(nil), frame...)
+	binary.BigEndian.PutUint32(huge[0:4], 1<<31)
+	if _, _, err := readSpoolFrame(bytes.NewReader(huge), 1<<20); err != errSpoolFrameSize {
+		t.Errorf("a 2 GiB length read with %v", err)
+	}
+	// the length is checksummed: a shorter one does not frame a record
+	short := append([]byte(nil), frame...)
+	binary.BigEndian.PutUint32(short[0:4], 3)
+	if _, _, err := readSpoolFrame(bytes.NewReader(short), 1<<20); err != errSpoolCorrupt {
+		t.Errorf("a changed length read with %v", err)
+	}
+}
+
+func TestSpoolAppendDuringReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var s *Spool
+	appended := make(chan error, 1)
+	s, err = OpenSpool(dir, 1<<20, func(m interface{}) (int64, error) {
+		if m.(*spoolTestRow).Name == "a" {
+			// the spool must not be locked while the store works
+			go func() { appended <- s.Append(&spoolTestRow{Name: "c"}) }()
+			select {
+			case err := <-appended:
+				if err != nil {
+					return 0, err
+				}
+			case <-time.After(5 * time.Second):
+				return 0, errors.New("append blocked by the replay")
+			}
+		}
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Append(&spoolTestRow{Name: "a"})
+	s.Append(&spoolTestRow{Name: "b"})
+	if err := s.Replay(); err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Status(); st.Records != 0 || st.Replayed != 3 {
+		t.Errorf("status after replay = %+v", st)
+	}
+}
@@ -1,5 +1,7 @@
 package sql_reg
 import (
+	"common"
+	"dao"
 	"fmt"
 
 	"github.com/astaxie/beego/orm"
@@ -10,9 +12,33 @@ func init() {
 	fmt.Println("Initializing DB registration.")
 	orm.RegisterDriver("mysql", orm.DRMySQL)
 	err := orm.RegisterDataBase("default", "mysql", "root:123456@tcp(10.110.18.107:30000)/k8s?charset=utf8")
-	orm.RunSyncdb("default", true, true)
+	syncErr := orm.RunSyncdb("default", false, true)
 	if err != nil {
 		fmt.Errorf("Error occurred on registering DB: %+v\n", err)
 	}
+	if createViews() && syncErr == nil {
+		// the readiness probe waits for this
+		if err := dao.Db_setSchemaVersion(dao.SchemaVersion, common.RecordNow()); err != nil {
+			fmt.Printf("Error occurred on recording the schema version: %+v\n", err)
+		}
+	}
+}
+
+// quota_usage shows used against hard per namespace and resource over time.
+const quotaUsageView = "CREATE OR REPLACE VIEW `quota_usage` AS" +
+	" SELECT `namespace`, `quota_name`, `resource`, `hard`, `used`, `hard_milli`, `used_milli`," +
+	" IF(`hard_milli` > 0, `used_milli` / `hard_milli`, NULL) AS `ratio`, `Record_time`, `tag`" +
+	" FROM `quotas`"
+
+func createViews() bool {
+	o := orm.NewOrm()
+	ok := true
+	for _, view := range []string{quotaUsageView} {
+		if _, err := o.Raw(view).Exec(); err != nil {
+			fmt.Printf("Error occurred on creating view: %+v\n", err)
+			ok = false
+		}
+	}
+	return ok
 }
 
@@ -0,0 +1,309 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Store is what the Db_ functions write through. DefaultStore is the MySQL
+// database; tests swap in a MemStore.
+type Store interface {
+	Insert(model interface{}) (int64, error)
+	Update(model interface{}, cols ...string) (int64, error)
+	UpdateByTag(table string, tag string, values orm.Params) (int64, error)
+	UpsertInventory(table string, uid string, namespace string, name string, now string, tag string) error
+	// MarkInventoryDeleted only looks at objects of namespaces, unless it
+	// is nil.
+	MarkInventoryDeleted(table string, tag string, now string, namespaces []string) (int64, error)
+	// MarkOrphanedInventoryDeleted looks at the objects of the namespaces
+	// not in namespaces that were not seen after now.
+	MarkOrphanedInventoryDeleted(table string, now string, namespaces []string) (int64, error)
+}
+
+var DefaultStore Store = ormStore{}
+
+type ormStore struct{}
+
+func (ormStore) Insert(model interface{}) (int64, error) {
+	return orm.NewOrm().Insert(model)
+}
+
+func (ormStore) Update(model interface{}, cols ...string) (int64, error) {
+	return orm.NewOrm().Update(model, cols...)
+}
+
+func (ormStore) UpdateByTag(table string, tag string, values orm.Params) (int64, error) {
+	return orm.NewOrm().QueryTable(table).Filter("tag", tag).Update(values)
+}
+
+func (ormStore) UpsertInventory(table string, uid string, namespace string, name string, now string, tag string) error {
+	o := orm.NewOrm()
+	_, err := o.Raw("INSERT INTO `"+table+"` (`uid`, `namespace`, `name`, `First_seen`, `Last_seen`, `Deleted_at`, `Lifetime`, `Last_tag`)"+
+		" VALUES (?, ?, ?, ?, ?, '', 0, ?)"+
+		" ON DUPLICATE KEY UPDATE `namespace` = VALUES(`namespace`), `name` = VALUES(`name`),"+
+		" `Last_seen` = VALUES(`Last_seen`), `Deleted_at` = '', `Last_tag` = VALUES(`Last_tag`),"+
+		" `Lifetime` = TIMESTAMPDIFF(SECOND, `First_seen`, VALUES(`Last_seen`))",
+		uid, namespace, name, now, now, tag).Exec()
+	return err
+}
+
+func (ormStore) MarkInventoryDeleted(table string, tag string, now string, namespaces []string) (int64, error) {
+	if namespaces != nil && len(namespaces) == 0 {
+		return 0, nil
+	}
+	query := "UPDATE `" + table + "` SET `Deleted_at` = ?, `Lifetime` = TIMESTAMPDIFF(SECOND, `First_seen`, ?)" +
+		" WHERE `Deleted_at` = '' AND `Last_tag` <> ?"
+	args := []interface{}{now, now, tag}
+	if namespaces != nil {
+		query += " AND `namespace` IN (?" + strings.Repeat(", ?", len(namespaces)-1) + ")"
+		for _, ns := range namespaces {
+			args = append(args, ns)
+		}
+	}
+	o := orm.NewOrm()
+	res, err := o.Raw(query, args...).Exec()
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (ormStore) MarkOrphanedInventoryDeleted(table string, now string, namespaces []string) (int64, error) {
+	query := "UPDATE `" + table + "` SET `Deleted_at` = ?, `Lifetime` = TIMESTAMPDIFF(SECOND, `First_seen`, ?)" +
+		" WHERE `Deleted_at` = '' AND `Last_seen` <= ?"
+	args := []interface{}{now, now, now}
+	if len(namespaces) > 0 {
+		query += " AND `namespace` NOT IN (?" + strings.Repeat(", ?", len(namespaces)-1) + ")"
+		for _, ns := range namespaces {
+			args = append(args, ns)
+		}
+	}
+	o := orm.NewOrm()
+	res, err := o.Raw(query, args...).Exec()
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// InventoryRow is a row of an inventory table kept by a MemStore. Lifetime
+// is not kept, it needs the time arithmetic of the database.
+type InventoryRow struct {
+	Uid        string
+	Namespace  string
+	Name       string
+	First_seen string
+	Last_seen  string
+	Deleted_at string
+	Last_tag   string
+}
+
+// MemStore keeps the rows in memory, by table name, for tests. Err, when
+// set, fails every write.
+type MemStore struct {
+	lock      sync.Mutex
+	rows      map[string][]reflect.Value
+	inventory map[string]map[string]*InventoryRow
+	lastId    int64
+	Err       error
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{rows: make(map[string][]reflect.Value), inventory: make(map[string]map[string]*InventoryRow)}
+}
+
+// tableName follows the orm naming: RunResults is stored in run_results.
+func tableName(t reflect.Type) string {
+	var b []byte
+	for i, r := range t.Name() {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b = append(b, '_')
+			}
+			r += 'a' - 'A'
+		}
+		b = append(b, byte(r))
+	}
+	return string(b)
+}
+
+func (m *MemStore) Insert(model interface{}) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return 0, errors.New("dao: insert needs a pointer to a struct")
+	}
+	m.lastId++
+	if id := v.Elem().FieldByName("Id"); id.IsValid() && id.CanSet() && id.Kind() == reflect.Int64 {
+		id.SetInt(m.lastId)
+	}
+	row := reflect.New(v.Elem().Type()).Elem()
+	row.Set(v.Elem())
+	table := tableName(row.Type())
+	m.rows[table] = append(m.rows[table], row)
+	return m.lastId, nil
+}
+
+func (m *MemStore) Update(model interface{}, cols ...string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	v := reflect.Indirect(reflect.ValueOf(model))
+	id := v.FieldByName("Id")
+	if !id.IsValid() {
+		return 0, errors.New("dao: update needs an Id field")
+	}
+	var n int64
+	for _, row := range m.rows[tableName(v.Type())] {
+		if row.FieldByName("Id").Int() != id.Int() {
+			continue
+		}
+		if len(cols) == 0 {
+			row.Set(v)
+		}
+		for _, col := range cols {
+			row.FieldByName(col).Set(v.FieldByName(col))
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (m *MemStore) UpdateByTag(table string, tag string, values orm.Params) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	var n int64
+	for _, row := range m.rows[table] {
+		if row.FieldByName("Tag").String() != tag {
+			continue
+		}
+		for col, value := range values {
+			f := row.FieldByName(col)
+			if !f.IsValid() {
+				return n, fmt.Errorf("dao: %s has no column %s", table, col)
+			}
+			f.Set(reflect.ValueOf(value).Convert(f.Type()))
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (m *MemStore) UpsertInventory(table string, uid string, namespace string, name string, now string, tag string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	rows := m.inventory[table]
+	if rows == nil {
+		rows = make(map[string]*InventoryRow)
+		m.inventory[table] = rows
+	}
+	row := rows[uid]
+	if row == nil {
+		row = &InventoryRow{Uid: uid, First_seen: now}
+		rows[uid] = row
+	}
+	row.Namespace, row.Name, row.Last_seen, row.Deleted_at, row.Last_tag = namespace, name, now, "", tag
+	return nil
+}
+
+func (m *MemStore) MarkInventoryDeleted(table string, tag string, now string, namespaces []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	var n int64
+	for _, row := range m.inventory[table] {
+		if namespaces != nil && !containsString(namespaces, row.Namespace) {
+			continue
+		}
+		if row.Deleted_at == "" && row.Last_tag != tag {
+			row.Deleted_at = now
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemStore) MarkOrphanedInventoryDeleted(table string, now string, namespaces []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	var n int64
+	for _, row := range m.inventory[table] {
+		if containsString(namespaces, row.Namespace) {
+			continue
+		}
+		if row.Deleted_at == "" && row.Last_seen <= now {
+			row.Deleted_at = now
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Rows returns copies of the rows of table, in insert order, as pointers to
+// their model type.
+func (m *MemStore) Rows(table string) []interface{} {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var rows []interface{}
+	for _, row := range m.rows[table] {
+		p := reflect.New(row.Type())
+		p.Elem().Set(row)
+		rows = append(rows, p.Interface())
+	}
+	return rows
+}
+
+// Inventory returns the inventory rows of table in no particular order.
+func (m *MemStore) Inventory(table string) []InventoryRow {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var rows []InventoryRow
+	for _, row := range m.inventory[table] {
+		rows = append(rows, *row)
+	}
+	return rows
+}
+
+// Tables lists the tables holding rows.
+func (m *MemStore) Tables() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var tables []string
+	for table := range m.rows {
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var _ Store = (*MemStore)(nil)
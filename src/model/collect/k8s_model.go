@@ -183,6 +183,10 @@ type PersistentVolumeSpec struct {
 	// More info: http://kubernetes.io/docs/user-guide/persistent-volumes#recycling-policy
 	// +optional
 	PersistentVolumeReclaimPolicy PersistentVolumeReclaimPolicy `json:"persistentVolumeReclaimPolicy,omitempty" protobuf:"bytes,5,opt,name=persistentVolumeReclaimPolicy,casttype=PersistentVolumeReclaimPolicy"`
+	// Name of StorageClass to which this persistent volume belongs. Added in
+	// 1.6, replacing the storage-class annotations.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty" protobuf:"bytes,6,opt,name=storageClassName"`
 }
 
 // PersistentVolumeReclaimPolicy describes a policy for end-of-life maintenance of persistent volumes.
@@ -278,6 +282,10 @@ type PersistentVolumeClaimSpec struct {
 	// VolumeName is the binding reference to the PersistentVolume backing this claim.
 	// +optional
 	VolumeName string `json:"volumeName,omitempty" protobuf:"bytes,3,opt,name=volumeName"`
+	// Name of the StorageClass required by the claim. Added in 1.6, replacing
+	// the storage-class annotations.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty" protobuf:"bytes,5,opt,name=storageClassName"`
 }
 
 // PersistentVolumeClaimStatus is the current status of a persistent volume claim.
@@ -2542,8 +2550,16 @@ type NodeSpec struct {
 	// More info: http://releases.k8s.io/HEAD/docs/admin/node.md#manual-node-administration"`
 	// +optional
 	Unschedulable bool `json:"unschedulable,omitempty" protobuf:"varint,4,opt,name=unschedulable"`
+	// If specified, the node's taints. Servers before 1.6 keep them in the
+	// TaintsAnnotationKey annotation instead.
+	// +optional
+	Taints []Taint `json:"taints,omitempty" protobuf:"bytes,5,rep,name=taints"`
 }
 
+// TaintsAnnotationKey is the annotation holding a node's JSON encoded taints
+// before they moved to NodeSpec.
+const TaintsAnnotationKey string = "scheduler.alpha.kubernetes.io/taints"
+
 // DaemonEndpoint contains information about a single Daemon endpoint.
 type DaemonEndpoint struct {
 	/*
@@ -2774,6 +2790,8 @@ const (
 	ResourceStorage ResourceName = "storage"
 	// NVIDIA GPU, in devices. Alpha, might change: although fractional and allowing values >1, only one whole device per node is assigned.
 	ResourceNvidiaGPU ResourceName = "alpha.kubernetes.io/nvidia-gpu"
+	// NVIDIA GPUs advertised by the device plugin, replacing ResourceNvidiaGPU from 1.10.
+	ResourceNvidiaGPUDevice ResourceName = "nvidia.com/gpu"
 	// Number of Pods that may be running on this Node: see ResourcePods
 )
 
@@ -1,9 +1,26 @@
 package collect
 
-import "github.com/astaxie/beego/orm"
+import (
+	"github.com/astaxie/beego/orm"
+	"dao"
+)
 
 func init() {
-	orm.RegisterModel(new(Nodes), new(Pods), new(Services))
+	orm.RegisterModel(new(Nodes), new(Pods), new(Services), new(Runs), new(RunResults))
+	orm.RegisterModel(new(PodInventory), new(NodeInventory), new(ServiceInventory))
+	orm.RegisterModel(new(Namespaces), new(Quotas), new(LimitRanges))
+	dao.RegisterSpoolModel(new(Nodes), new(Pods), new(Services), new(Runs), new(RunResults))
+	dao.RegisterSpoolModel(new(Namespaces), new(Quotas), new(LimitRanges))
+	orm.RegisterModel(new(PersistentVolumes), new(PersistentVolumeClaims), new(PodVolumeClaims))
+	dao.RegisterSpoolModel(new(PersistentVolumes), new(PersistentVolumeClaims), new(PodVolumeClaims))
+	orm.RegisterModel(new(NodeConditions), new(ServiceEndpoints), new(ObjectLabels))
+	dao.RegisterSpoolModel(new(NodeConditions), new(ServiceEndpoints), new(ObjectLabels))
+	orm.RegisterModel(new(Workloads), new(GenericObjects), new(GenericFields))
+	dao.RegisterSpoolModel(new(Workloads), new(GenericObjects), new(GenericFields))
+	orm.RegisterModel(new(CollectorMembers))
+	orm.RegisterModel(new(SchemaVersions))
+	orm.RegisterModel(new(AuditLog))
+	dao.RegisterSpoolModel(new(AuditLog))
 }
 type Nodes struct {
 	Id               int64    `json:"id" orm:"pk;auto"`
@@ -12,30 +29,392 @@ type Nodes struct {
 	Numbers_gpu_core string  `json:"pod_name" orm:"column(Numbers_gpu_core)"`
 	Memory_size      string  `json:"pod_name" orm:"column(Memory_size)"`
 	Pod_limit        string  `json:"pod_name" orm:"column(Pod_limit)"`
+	Allocatable_cpu    string `json:"Allocatable_cpu" orm:"column(Allocatable_cpu)"`
+	Allocatable_gpu    string `json:"Allocatable_gpu" orm:"column(Allocatable_gpu)"`
+	Allocatable_memory string `json:"Allocatable_memory" orm:"column(Allocatable_memory)"`
+	Allocatable_pods   string `json:"Allocatable_pods" orm:"column(Allocatable_pods)"`
+	Ready              string `json:"Ready" orm:"column(Ready)"`
+	Unschedulable      bool   `json:"Unschedulable" orm:"column(Unschedulable)"`
+	Kernel_version     string `json:"Kernel_version" orm:"column(Kernel_version)"`
+	Os_image           string `json:"Os_image" orm:"column(Os_image)"`
+	Operating_system   string `json:"Operating_system" orm:"column(Operating_system)"`
+	Architecture       string `json:"Architecture" orm:"column(Architecture)"`
+	Runtime_version    string `json:"Runtime_version" orm:"column(Runtime_version)"`
+	Kubelet_version    string `json:"Kubelet_version" orm:"column(Kubelet_version);index"`
+	Kube_proxy_version string `json:"Kube_proxy_version" orm:"column(Kube_proxy_version)"`
+	Addresses          string `json:"Addresses" orm:"column(Addresses)"`
+	Taints             string `json:"Taints" orm:"column(Taints)"`
 	Create_time      string `json:"Creat_time" orm:"column(Create_time)"`
 	Record_time      string `json:"Record_time" orm:"column(Record_time)"`
-	Tag              string `json:"tag" orm:"column(tag)"`
+	Tag              string `json:"tag" orm:"column(tag);index"`
+}
+
+// NodeConditions holds the conditions of every node per run, so readiness and
+// pressure transitions can be followed over time.
+type NodeConditions struct {
+	Id                   int64  `json:"id" orm:"pk;auto"`
+	Node_name            string `json:"node_name" orm:"column(Node_name);index"`
+	Type                 string `json:"type" orm:"column(type)"`
+	Status               string `json:"status" orm:"column(status)"`
+	Reason               string `json:"reason" orm:"column(reason)"`
+	Message              string `json:"message" orm:"column(message);type(text)"`
+	Last_heartbeat_time  string `json:"Last_heartbeat_time" orm:"column(Last_heartbeat_time)"`
+	Last_transition_time string `json:"Last_transition_time" orm:"column(Last_transition_time)"`
+	Record_time          string `json:"Record_time" orm:"column(Record_time);index"`
+	Tag                  string `json:"tag" orm:"column(tag);index"`
 }
 
 type Pods struct {
 	Id                   int64    `json:"id" orm:"pk;auto"`
+	Namespace             string `json:"namespace" orm:"column(namespace);index"`
 	Pod_name              string `json:"pod_name" orm:"column(pod_name)"`
-	Pod_hostIP            string`json:"pod_name" orm:"column(pod_hostIP)"`
-	Containers_numbers    string`json:"pod_name" orm:"column(containers_numbers)"`
+	Uid                   string `json:"uid" orm:"column(uid);size(64);index"`
+	Phase                 string `json:"phase" orm:"column(phase)"`
+	Reason                string `json:"reason" orm:"column(reason)"`
+	Message               string `json:"message" orm:"column(message);type(text)"`
+	Pod_hostIP            string `json:"pod_hostIP" orm:"column(pod_hostIP)"`
+	Pod_IP                string `json:"pod_IP" orm:"column(pod_IP)"`
+	Node_name             string `json:"node_name" orm:"column(node_name);index"`
+	Restart_policy        string `json:"restart_policy" orm:"column(restart_policy)"`
+	Qos_class             string `json:"qos_class" orm:"column(qos_class)"`
+	Owner_kind            string `json:"owner_kind" orm:"column(owner_kind)"`
+	Owner_name            string `json:"owner_name" orm:"column(owner_name)"`
+	Owner_uid             string `json:"owner_uid" orm:"column(owner_uid);size(64)"`
+	Containers_numbers    string `json:"containers_numbers" orm:"column(containers_numbers)"`
+	Start_time            string `json:"Start_time" orm:"column(start_time)"`
 	Create_time           string `json:"Creat_time" orm:"column(create_time)"`
 	Record_time           string `json:"Record_time" orm:"column(Record_time)"`
 	All_pod_numbers       string `json:"All_pod_numbers" orm:"column(All_pod_numbers)"`
 	All_container_numbers string `json:"All_container_numbers" orm:"column(All_container_numbers)"`
-	Tag                   string `json:"tag" orm:"column(tag)"`
+	Restart_count         string `json:"Restart_count" orm:"column(Restart_count)"`
+	Tag                   string `json:"tag" orm:"column(tag);index"`
 }
 
 type Services struct {
 	Id             int64    `json:"id" orm:"pk;auto"`
+	Namespace       string `json:"namespace" orm:"column(namespace);index"`
 	Service_name    string `json:"pod_name" orm:"column(Service_name)"`
 	Service_numbers string `json:"pod_name" orm:"column(Service_numbers)"`
+	Uid             string `json:"uid" orm:"column(uid);size(64)"`
+	Type            string `json:"type" orm:"column(type)"`
+	Cluster_ip      string `json:"cluster_ip" orm:"column(cluster_ip)"`
+	Ports           string `json:"ports" orm:"column(ports);type(text)"`
+	Selector        string `json:"selector" orm:"column(selector);type(text)"`
+	External_ips    string `json:"external_ips" orm:"column(external_ips)"`
+	Lb_ingress      string `json:"lb_ingress" orm:"column(lb_ingress)"`
+	External_name   string `json:"external_name" orm:"column(external_name)"`
+	Affinity        string `json:"session_affinity" orm:"column(session_affinity)"`
 	Create_time     string `json:"Creat_time" orm:"column(Creat_time)"`
 	Record_time     string `json:"Record_time" orm:"column(Record_time)"`
-	Tag             string `json:"tag" orm:"column(tag)"`
+	Tag             string `json:"tag" orm:"column(tag);index"`
+}
+
+// ServiceEndpoints summarises the Endpoints object behind a service. The pod
+// columns list the namespace/name of the pods the addresses point at.
+type ServiceEndpoints struct {
+	Id                  int64  `json:"id" orm:"pk;auto"`
+	Namespace           string `json:"namespace" orm:"column(namespace);index"`
+	Service_name        string `json:"service_name" orm:"column(Service_name)"`
+	Ready_addresses     int    `json:"ready_addresses" orm:"column(ready_addresses)"`
+	Not_ready_addresses int    `json:"not_ready_addresses" orm:"column(not_ready_addresses)"`
+	Ready_pods          string `json:"ready_pods" orm:"column(ready_pods);type(text)"`
+	Not_ready_pods      string `json:"not_ready_pods" orm:"column(not_ready_pods);type(text)"`
+	Ports               string `json:"ports" orm:"column(ports)"`
+	Record_time         string `json:"Record_time" orm:"column(Record_time)"`
+	Tag                 string `json:"tag" orm:"column(tag);index"`
+}
+
+type Namespaces struct {
+	Id             int64  `json:"id" orm:"pk;auto"`
+	Namespace_name string `json:"namespace" orm:"column(namespace)"`
+	Phase          string `json:"phase" orm:"column(phase)"`
+	Create_time    string `json:"Creat_time" orm:"column(Create_time)"`
+	Record_time    string `json:"Record_time" orm:"column(Record_time)"`
+	Tag            string `json:"tag" orm:"column(tag);index"`
+}
+
+// Quotas holds one row per quota and resource; the milli columns carry
+// the quantities as numbers so used and hard can be compared in SQL.
+type Quotas struct {
+	Id          int64  `json:"id" orm:"pk;auto"`
+	Namespace   string `json:"namespace" orm:"column(namespace);index"`
+	Quota_name  string `json:"quota_name" orm:"column(quota_name)"`
+	Resource    string `json:"resource" orm:"column(resource)"`
+	Hard        string `json:"hard" orm:"column(hard)"`
+	Used        string `json:"used" orm:"column(used)"`
+	Hard_milli  int64  `json:"hard_milli" orm:"column(hard_milli)"`
+	Used_milli  int64  `json:"used_milli" orm:"column(used_milli)"`
+	Scopes      string `json:"scopes" orm:"column(scopes)"`
+	Record_time string `json:"Record_time" orm:"column(Record_time);index"`
+	Tag         string `json:"tag" orm:"column(tag);index"`
+}
+
+// LimitRanges holds one row per limit range item and resource.
+type LimitRanges struct {
+	Id              int64  `json:"id" orm:"pk;auto"`
+	Namespace       string `json:"namespace" orm:"column(namespace);index"`
+	Limit_name      string `json:"limit_name" orm:"column(limit_name)"`
+	Type            string `json:"type" orm:"column(type)"`
+	Resource        string `json:"resource" orm:"column(resource)"`
+	Min             string `json:"min" orm:"column(min)"`
+	Max             string `json:"max" orm:"column(max)"`
+	Default         string `json:"default" orm:"column(default_limit)"`
+	Default_request string `json:"default_request" orm:"column(default_request)"`
+	Max_ratio       string `json:"max_limit_request_ratio" orm:"column(max_ratio)"`
+	Record_time     string `json:"Record_time" orm:"column(Record_time)"`
+	Tag             string `json:"tag" orm:"column(tag);index"`
 }
 
+type PersistentVolumes struct {
+	Id              int64  `json:"id" orm:"pk;auto"`
+	Volume_name     string `json:"volume_name" orm:"column(volume_name)"`
+	Capacity        string `json:"capacity" orm:"column(capacity)"`
+	Access_modes    string `json:"access_modes" orm:"column(access_modes)"`
+	Reclaim_policy  string `json:"reclaim_policy" orm:"column(reclaim_policy)"`
+	Storage_class   string `json:"storage_class" orm:"column(storage_class)"`
+	Phase           string `json:"phase" orm:"column(phase);index"`
+	Reason          string `json:"reason" orm:"column(reason)"`
+	Claim_namespace string `json:"claim_namespace" orm:"column(claim_namespace)"`
+	Claim_name      string `json:"claim_name" orm:"column(claim_name)"`
+	Create_time     string `json:"Creat_time" orm:"column(Create_time)"`
+	Record_time     string `json:"Record_time" orm:"column(Record_time)"`
+	Tag             string `json:"tag" orm:"column(tag);index"`
+}
 
+type PersistentVolumeClaims struct {
+	Id            int64  `json:"id" orm:"pk;auto"`
+	Namespace     string `json:"namespace" orm:"column(namespace);index"`
+	Claim_name    string `json:"claim_name" orm:"column(claim_name)"`
+	Phase         string `json:"phase" orm:"column(phase);index"`
+	Volume_name   string `json:"volume_name" orm:"column(volume_name)"`
+	Requested     string `json:"requested" orm:"column(requested)"`
+	Capacity      string `json:"capacity" orm:"column(capacity)"`
+	Access_modes  string `json:"access_modes" orm:"column(access_modes)"`
+	Storage_class string `json:"storage_class" orm:"column(storage_class)"`
+	Create_time   string `json:"Creat_time" orm:"column(Create_time)"`
+	Record_time   string `json:"Record_time" orm:"column(Record_time)"`
+	Tag           string `json:"tag" orm:"column(tag);index"`
+}
+
+// PodVolumeClaims links a pod to each claim it mounts.
+type PodVolumeClaims struct {
+	Id          int64  `json:"id" orm:"pk;auto"`
+	Namespace   string `json:"namespace" orm:"column(namespace)"`
+	Pod_name    string `json:"pod_name" orm:"column(pod_name)"`
+	Volume      string `json:"volume" orm:"column(volume)"`
+	Claim_name  string `json:"claim_name" orm:"column(claim_name)"`
+	Read_only   bool   `json:"read_only" orm:"column(read_only)"`
+	Record_time string `json:"Record_time" orm:"column(Record_time)"`
+	Tag         string `json:"tag" orm:"column(tag);index"`
+}
+
+// Workloads holds one row per workload controller and run. Desired, Current,
+// Updated, Ready and Available are replica counts; DaemonSets count nodes and
+// Jobs store completions, active and succeeded pods in Desired, Current and
+// Ready. Converged is set when the controller has observed the latest
+// generation and all the counts reached Desired.
+type Workloads struct {
+	Id                  int64  `json:"id" orm:"pk;auto"`
+	Kind                string `json:"kind" orm:"column(kind)"`
+	Namespace           string `json:"namespace" orm:"column(namespace)"`
+	Workload_name       string `json:"workload_name" orm:"column(workload_name)"`
+	Uid                 string `json:"uid" orm:"column(uid);size(64);index"`
+	Desired             int    `json:"desired" orm:"column(desired)"`
+	Current             int    `json:"current" orm:"column(current)"`
+	Updated             int    `json:"updated" orm:"column(updated)"`
+	Ready               int    `json:"ready" orm:"column(ready)"`
+	Available           int    `json:"available" orm:"column(available)"`
+	Failed              int    `json:"failed" orm:"column(failed)"`
+	Generation          int64  `json:"generation" orm:"column(generation)"`
+	Observed_generation int64  `json:"observed_generation" orm:"column(observed_generation)"`
+	Converged           bool   `json:"converged" orm:"column(converged)"`
+	Paused              bool   `json:"paused" orm:"column(paused)"`
+	Reason              string `json:"reason" orm:"column(reason)"`
+	Selector            string `json:"selector" orm:"column(selector);type(text)"`
+	Owner_kind          string `json:"owner_kind" orm:"column(owner_kind)"`
+	Owner_name          string `json:"owner_name" orm:"column(owner_name)"`
+	Owner_uid           string `json:"owner_uid" orm:"column(owner_uid)"`
+	Create_time         string `json:"Create_time" orm:"column(Create_time)"`
+	Record_time         string `json:"Record_time" orm:"column(Record_time)"`
+	Tag                 string `json:"tag" orm:"column(tag);index"`
+}
+
+// GenericObjects stores the objects of the kinds collected without a typed
+// model, as their raw JSON document.
+type GenericObjects struct {
+	Id               int64  `json:"id" orm:"pk;auto"`
+	Kind             string `json:"kind" orm:"column(kind)"`
+	Namespace        string `json:"namespace" orm:"column(namespace)"`
+	Name             string `json:"name" orm:"column(name)"`
+	Uid              string `json:"uid" orm:"column(uid);size(64);index"`
+	Resource_version string `json:"resource_version" orm:"column(resource_version)"`
+	Labels           string `json:"labels" orm:"column(labels);type(text)"`
+	Document         string `json:"document" orm:"column(document);type(text)"`
+	Record_time      string `json:"Record_time" orm:"column(Record_time)"`
+	Tag              string `json:"tag" orm:"column(tag)"`
+}
+
+func (g *GenericObjects) TableIndex() [][]string {
+	return [][]string{{"Tag", "Kind"}}
+}
+
+// GenericFields holds the values the configured JSONPath expressions extract
+// from generic objects, one row per object and field.
+type GenericFields struct {
+	Id          int64  `json:"id" orm:"pk;auto"`
+	Kind        string `json:"kind" orm:"column(kind)"`
+	Uid         string `json:"uid" orm:"column(uid);size(64)"`
+	Field       string `json:"field" orm:"column(field);size(64)"`
+	Value       string `json:"value" orm:"column(value);size(255)"`
+	Record_time string `json:"Record_time" orm:"column(Record_time)"`
+	Tag         string `json:"tag" orm:"column(tag)"`
+}
+
+func (g *GenericFields) TableIndex() [][]string {
+	return [][]string{{"Tag", "Kind", "Field", "Value"}}
+}
+
+// ObjectLabels holds one row per label, or allow-listed annotation, of every
+// collected object. Kind is the lower case plural resource name.
+type ObjectLabels struct {
+	Id          int64  `json:"id" orm:"pk;auto"`
+	Kind        string `json:"kind" orm:"column(kind)"`
+	Namespace   string `json:"namespace" orm:"column(namespace)"`
+	Name        string `json:"name" orm:"column(name)"`
+	Uid         string `json:"uid" orm:"column(uid);size(64)"`
+	Key         string `json:"key" orm:"column(label_key);size(255);index"`
+	Value       string `json:"value" orm:"column(label_value);type(text)"`
+	Annotation  bool   `json:"annotation" orm:"column(annotation)"`
+	Record_time string `json:"Record_time" orm:"column(Record_time)"`
+	Tag         string `json:"tag" orm:"column(tag)"`
+}
+
+func (l *ObjectLabels) TableIndex() [][]string {
+	return [][]string{{"Tag", "Kind"}}
+}
+
+// Runs records one collection cycle. Rows of the history tables carry the run
+// tag, so a run ties together everything collected in the same cycle.
+type Runs struct {
+	Id         int64  `json:"id" orm:"pk;auto"`
+	Tag        string `json:"tag" orm:"column(tag);index"`
+	Start_time string `json:"Start_time" orm:"column(Start_time);index"`
+	End_time   string `json:"End_time" orm:"column(End_time)"`
+	Status     string `json:"Status" orm:"column(Status)"`
+	// Server_version is the apiserver git version found by discovery.
+	Server_version string `json:"Server_version" orm:"column(Server_version)"`
+	// Cluster is the configured cluster name, or the one given to an import.
+	Cluster string `json:"Cluster" orm:"column(Cluster);index"`
+	// Shard names the replica and the number of replicas when the
+	// collection is sharded.
+	Shard string `json:"Shard" orm:"column(Shard)"`
+}
+
+const (
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunPartial   = "partial"
+	// the collector shut down before the run finished
+	RunInterrupted = "interrupted"
+)
+
+// RunResults records how each collector did in a run.
+type RunResults struct {
+	Id          int64  `json:"id" orm:"pk;auto"`
+	Run_id      int64  `json:"run_id" orm:"column(run_id);index"`
+	Tag         string `json:"tag" orm:"column(tag);index"`
+	Kind        string `json:"kind" orm:"column(kind)"`
+	Status      string `json:"status" orm:"column(status)"`
+	Items       int    `json:"items" orm:"column(items)"`
+	Duration_ms int64  `json:"duration_ms" orm:"column(duration_ms)"`
+	Bytes       int64  `json:"bytes" orm:"column(bytes)"`
+	Encoding    string `json:"encoding" orm:"column(encoding)"`
+	Error       string `json:"error" orm:"column(error);type(text)"`
+}
+
+const (
+	CollectorOk       = "ok"
+	CollectorFailed   = "failed"
+	CollectorSkipped  = "skipped"
+	CollectorDisabled = "disabled"
+	// the cluster serves none of the group versions the collector reads
+	CollectorUnsupported = "unsupported"
+	// another replica collects the kind
+	CollectorOtherShard = "other-shard"
+)
+
+// The inventory tables hold the current state of every object ever seen, one
+// row per UID. Deleted_at is empty while the object still exists; Lifetime is
+// the number of seconds between First_seen and the last sighting or deletion.
+type PodInventory struct {
+	Uid        string `json:"uid" orm:"pk;column(uid);size(64)"`
+	Namespace  string `json:"namespace" orm:"column(namespace);index"`
+	Name       string `json:"name" orm:"column(name)"`
+	First_seen string `json:"First_seen" orm:"column(First_seen);index"`
+	Last_seen  string `json:"Last_seen" orm:"column(Last_seen)"`
+	Deleted_at string `json:"Deleted_at" orm:"column(Deleted_at);index"`
+	Lifetime   int64  `json:"Lifetime" orm:"column(Lifetime)"`
+	Last_tag   string `json:"tag" orm:"column(Last_tag)"`
+}
+
+type NodeInventory struct {
+	Uid        string `json:"uid" orm:"pk;column(uid);size(64)"`
+	Namespace  string `json:"namespace" orm:"column(namespace);index"`
+	Name       string `json:"name" orm:"column(name)"`
+	First_seen string `json:"First_seen" orm:"column(First_seen);index"`
+	Last_seen  string `json:"Last_seen" orm:"column(Last_seen)"`
+	Deleted_at string `json:"Deleted_at" orm:"column(Deleted_at);index"`
+	Lifetime   int64  `json:"Lifetime" orm:"column(Lifetime)"`
+	Last_tag   string `json:"tag" orm:"column(Last_tag)"`
+}
+
+type ServiceInventory struct {
+	Uid        string `json:"uid" orm:"pk;column(uid);size(64)"`
+	Namespace  string `json:"namespace" orm:"column(namespace);index"`
+	Name       string `json:"name" orm:"column(name)"`
+	First_seen string `json:"First_seen" orm:"column(First_seen);index"`
+	Last_seen  string `json:"Last_seen" orm:"column(Last_seen)"`
+	Deleted_at string `json:"Deleted_at" orm:"column(Deleted_at);index"`
+	Lifetime   int64  `json:"Lifetime" orm:"column(Lifetime)"`
+	Last_tag   string `json:"tag" orm:"column(Last_tag)"`
+}
+
+// InventoryTables maps the collected kinds to their inventory table.
+var InventoryTables = map[string]string{
+	"pods":     "pod_inventory",
+	"nodes":    "node_inventory",
+	"services": "service_inventory",
+}
+
+// CollectorMembers lists the replicas sharing the collection; a replica is a
+// member while its Heartbeat is recent.
+type CollectorMembers struct {
+	Identity  string `json:"identity" orm:"pk;column(identity);size(128)"`
+	Started   string `json:"Started" orm:"column(Started)"`
+	Heartbeat string `json:"Heartbeat" orm:"column(Heartbeat);index"`
+}
+
+// SchemaVersions records every schema version the tables were synced to.
+type SchemaVersions struct {
+	Version int    `json:"version" orm:"pk;column(version)"`
+	Applied string `json:"Applied" orm:"column(Applied)"`
+}
+
+// AuditLog is the append-only trail of the control calls, one row per call
+// whether it was let through or not.
+type AuditLog struct {
+	Id          int64  `json:"id" orm:"pk;auto"`
+	Record_time string `json:"Record_time" orm:"column(Record_time);index"`
+	// Caller is empty when the call was not authenticated.
+	Caller      string `json:"caller" orm:"column(caller);size(128);index"`
+	Auth_method string `json:"auth_method" orm:"column(auth_method)"`
+	Role        string `json:"role" orm:"column(role)"`
+	Remote_addr string `json:"remote_addr" orm:"column(remote_addr)"`
+	Method      string `json:"method" orm:"column(method)"`
+	Route       string `json:"route" orm:"column(route)"`
+	Path        string `json:"path" orm:"column(path)"`
+	Params      string `json:"params" orm:"column(params);type(text)"`
+	Status      int    `json:"status" orm:"column(status)"`
+	Result      string `json:"result" orm:"column(result)"`
+	Latency_ms  int64  `json:"latency_ms" orm:"column(latency_ms)"`
+}
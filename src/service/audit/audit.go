@@ -0,0 +1,117 @@
+// Package audit records who called which control route, with what and how it
+// ended, in the audit_log table and optionally in a JSON lines file.
+package audit
+
+import (
+	"common"
+	"dao"
+	"encoding/json"
+	model "model/collect"
+	"net/http"
+	"os"
+	"service/auth"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	fileLock sync.Mutex
+	file     *os.File
+)
+
+// Open mirrors the trail to path, appending to what it holds.
+func Open(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	fileLock.Lock()
+	defer fileLock.Unlock()
+	file = f
+	common.DebugPrint("mirroring the audit log to", path)
+	return nil
+}
+
+// Close stops mirroring to the file.
+func Close() error {
+	fileLock.Lock()
+	defer fileLock.Unlock()
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	return err
+}
+
+// Record stores e. A database that is down gets it through the spool; the
+// file gets it at once.
+func Record(e *model.AuditLog) {
+	if _, err := dao.Db_insert(e); err != nil && err != dao.ErrSpooled {
+		common.LogErr(err)
+	}
+	fileLock.Lock()
+	defer fileLock.Unlock()
+	if file == nil {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err == nil {
+		_, err = file.Write(append(line, '\n'))
+	}
+	common.LogErr(err)
+}
+
+// statusWriter remembers the status code inner answered with.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Middleware records every call of the route name. It goes outside the auth
+// check so denied calls are recorded too. The parameters are the route
+// variables and the query; bodies are left out, they may hold secrets.
+func Middleware(name string, inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, caller := auth.Track(r.Context())
+		sw := &statusWriter{ResponseWriter: w}
+		inner.ServeHTTP(sw, r.WithContext(ctx))
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		params, _ := json.Marshal(map[string]interface{}{"vars": mux.Vars(r), "query": r.URL.Query()})
+		e := &model.AuditLog{
+			Record_time: common.RecordTime(start),
+			Remote_addr: r.RemoteAddr,
+			Method:      r.Method,
+			Route:       name,
+			Path:        r.URL.Path,
+			Params:      string(params),
+			Status:      sw.status,
+			Result:      http.StatusText(sw.status),
+			Latency_ms:  int64(time.Since(start) / time.Millisecond),
+		}
+		if id, ok := caller(); ok {
+			e.Caller, e.Auth_method, e.Role = id.Name, id.Method, id.Role.String()
+		}
+		Record(e)
+	})
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"bufio"
+	"dao"
+	"encoding/json"
+	model "model/collect"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"service/auth"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMiddleware(t *testing.T) {
+	store := dao.NewMemStore()
+	defaultStore := dao.DefaultStore
+	dao.DefaultStore = store
+	defer func() { dao.DefaultStore = defaultStore }()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	a, _ := auth.New(auth.Config{Tokens: []auth.Token{
+		{Name: "dashboard", Token: "r3ad", Role: auth.Reader},
+		{Name: "deploy", Token: "0perate", Role: auth.Operator},
+	}})
+	router := mux.NewRouter()
+	router.Methods("POST").Path("/status/{status}").Handler(Middleware("getStatus",
+		a.Require(auth.Operator, "getStatus", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("post turn off\n"))
+		}))))
+	for _, token := range []string{"", "r3ad", "0perate"} {
+		r := httptest.NewRequest("POST", "/status/false?why=maintenance", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	rows := store.Rows("audit_log")
+	if len(rows) != 3 {
+		t.Fatalf("%d audit rows", len(rows))
+	}
+	want := []struct {
+		caller string
+		status int
+	}{{"", 401}, {"dashboard", 403}, {"deploy", 200}}
+	for i, row := range rows {
+		e := row.(*model.AuditLog)
+		if e.Caller != want[i].caller || e.Status != want[i].status || e.Route != "getStatus" {
+			t.Errorf("row %d = %+v", i, e)
+		}
+		if !strings.Contains(e.Params, `"status":"false"`) || !strings.Contains(e.Params, `"why":["maintenance"]`) {
+			t.Errorf("params = %s", e.Params)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines := bufio.NewScanner(f)
+	var mirrored []model.AuditLog
+	for lines.Scan() {
+		var e model.AuditLog
+		if err := json.Unmarshal(lines.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		mirrored = append(mirrored, e)
+	}
+	if len(mirrored) != 3 || mirrored[2].Caller != "deploy" || mirrored[2].Role != "operator" {
+		t.Errorf("mirrored = %+v", mirrored)
+	}
+}
@@ -0,0 +1,224 @@
+// Package auth authenticates REST API requests by bearer token, either a
+// static token or an HMAC-signed JWT, or by verified client certificate, and
+// checks the role they carry.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Role is what a caller may do; each role may do what the ones below it may.
+type Role int
+
+const (
+	// Reader uses the query APIs.
+	Reader Role = iota
+	// Operator also controls the collector.
+	Operator
+	// Admin also takes destructive actions.
+	Admin
+)
+
+var roleNames = []string{"reader", "operator", "admin"}
+
+func (r Role) String() string {
+	if r < 0 || int(r) >= len(roleNames) {
+		return fmt.Sprintf("role(%d)", int(r))
+	}
+	return roleNames[r]
+}
+
+func ParseRole(s string) (Role, error) {
+	for i, name := range roleNames {
+		if s == name {
+			return Role(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown role %q", s)
+}
+
+func (r *Role) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	role, err := ParseRole(s)
+	*r = role
+	return err
+}
+
+func (r Role) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// Token is a static bearer token.
+type Token struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	Role  Role   `json:"role"`
+}
+
+// Config is the auth config file.
+type Config struct {
+	Tokens []Token `json:"tokens"`
+	// Jwt accepts tokens signed with HS256, HS384 or HS512.
+	Jwt *JwtConfig `json:"jwt"`
+	// Client_certs maps the CN of verified client certificates to roles.
+	Client_certs map[string]Role `json:"client_certs"`
+}
+
+// Identity is the authenticated caller.
+type Identity struct {
+	Name   string `json:"name"`
+	Role   Role   `json:"role"`
+	Method string `json:"method"`
+}
+
+var (
+	ErrNoCredentials = errors.New("no bearer token")
+	ErrInvalid       = errors.New("invalid bearer token")
+)
+
+type Authenticator struct {
+	// tokens is keyed by the digest of the token, so the lookup does not
+	// leak the tokens through timing.
+	tokens map[[sha256.Size]byte]Token
+	jwt    *JwtConfig
+	certs  map[string]Role
+	now    func() time.Time
+}
+
+// Load reads the config file path.
+func Load(path string) (*Authenticator, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return New(c)
+}
+
+func New(c Config) (*Authenticator, error) {
+	a := &Authenticator{tokens: make(map[[sha256.Size]byte]Token), jwt: c.Jwt, certs: c.Client_certs, now: time.Now}
+	for _, t := range c.Tokens {
+		if t.Token == "" {
+			return nil, fmt.Errorf("token %q is empty", t.Name)
+		}
+		a.tokens[sha256.Sum256([]byte(t.Token))] = t
+	}
+	if c.Jwt != nil && c.Jwt.Secret == "" {
+		return nil, errors.New("jwt secret is empty")
+	}
+	if len(c.Tokens) == 0 && c.Jwt == nil && len(c.Client_certs) == 0 {
+		return nil, errors.New("no tokens, jwt secret or client certificates, nobody could authenticate")
+	}
+	return a, nil
+}
+
+// Authenticate identifies the caller of r from its Authorization header, or
+// without one from its client certificate.
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return a.clientCert(r)
+	}
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return Identity{}, ErrInvalid
+	}
+	bearer := strings.TrimSpace(header[7:])
+	digest := sha256.Sum256([]byte(bearer))
+	if t, ok := a.tokens[digest]; ok && subtle.ConstantTimeCompare([]byte(t.Token), []byte(bearer)) == 1 {
+		return Identity{Name: t.Name, Role: t.Role, Method: "token"}, nil
+	}
+	if a.jwt != nil && strings.Count(bearer, ".") == 2 {
+		return a.jwt.verify(bearer, a.now())
+	}
+	return Identity{}, ErrInvalid
+}
+
+// clientCert identifies the caller by the CN of a client certificate the TLS
+// handshake verified.
+func (a *Authenticator) clientCert(r *http.Request) (Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return Identity{}, ErrNoCredentials
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	role, ok := a.certs[cn]
+	if !ok {
+		return Identity{}, fmt.Errorf("client certificate %q has no role", cn)
+	}
+	return Identity{Name: cn, Role: role, Method: "cert"}, nil
+}
+
+type identityKey struct{}
+
+type trackKey struct{}
+
+type tracked struct {
+	id Identity
+	ok bool
+}
+
+// Track returns a context in which Require notes the caller it identified,
+// also when it then denied the call, and the func reading it back once the
+// request was served.
+func Track(ctx context.Context) (context.Context, func() (Identity, bool)) {
+	t := &tracked{}
+	return context.WithValue(ctx, trackKey{}, t), func() (Identity, bool) { return t.id, t.ok }
+}
+
+func note(r *http.Request, id Identity) {
+	if t, ok := r.Context().Value(trackKey{}).(*tracked); ok {
+		t.id, t.ok = id, true
+	}
+}
+
+// FromContext returns the caller Require let through, false on public
+// routes and when auth is off.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// Require lets requests through to inner only from callers with role or a
+// higher one, answering 401 to anonymous and badly authenticated requests
+// and 403 to callers with a lower role. Denials are logged.
+func (a *Authenticator) Require(role Role, name string, inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := a.Authenticate(r)
+		if err != nil {
+			deny(w, r, name, http.StatusUnauthorized, err.Error())
+			return
+		}
+		note(r, id)
+		if id.Role < role {
+			deny(w, r, name, http.StatusForbidden, fmt.Sprintf("%s %s has role %s, %s needs %s", id.Method, id.Name, id.Role, name, role))
+			return
+		}
+		inner.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+	})
+}
+
+func deny(w http.ResponseWriter, r *http.Request, name string, code int, reason string) {
+	log.Printf("%s\t%s\t%s\tdenied %d\t%s\t%s", r.Method, r.RequestURI, name, code, r.RemoteAddr, reason)
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="jobplatform"`)
+	}
+	w.Header().Set("Content-Type", "application/json;   charset=UTF-8")
+	w.WriteHeader(code)
+	body, _ := json.Marshal(map[string]string{"error": http.StatusText(code)})
+	w.Write(append(body, '\n'))
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func signJWT(alg string, secret string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, err := New(Config{
+		Tokens: []Token{{Name: "dashboard", Token: "r3ad", Role: Reader}},
+		Jwt:    &JwtConfig{Secret: "s3cret", Issuer: "ops", Audience: "collector"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1500000000, 0)
+	a.now = func() time.Time { return now }
+	valid := map[string]interface{}{"sub": "alice", "role": "operator", "iss": "ops", "aud": []string{"collector"}, "exp": now.Unix() + 60}
+	with := func(k string, v interface{}) map[string]interface{} {
+		c := make(map[string]interface{})
+		for k, v := range valid {
+			c[k] = v
+		}
+		c[k] = v
+		return c
+	}
+	for _, c := range []struct {
+		name   string
+		header string
+		want   Identity
+		ok     bool
+	}{
+		{"token", "Bearer r3ad", Identity{Name: "dashboard", Role: Reader, Method: "token"}, true},
+		{"jwt", "bearer " + signJWT("HS256", "s3cret", valid), Identity{Name: "alice", Role: Operator, Method: "jwt"}, true},
+		{"none", "", Identity{}, false},
+		{"basic", "Basic cjNhZA==", Identity{}, false},
+		{"wrong token", "Bearer r3ad!", Identity{}, false},
+		{"wrong secret", "Bearer " + signJWT("HS256", "guess", valid), Identity{}, false},
+		{"alg none", "Bearer " + signJWT("none", "s3cret", valid), Identity{}, false},
+		{"expired", "Bearer " + signJWT("HS256", "s3cret", with("exp", now.Unix()-1)), Identity{}, false},
+		{"no exp", "Bearer " + signJWT("HS256", "s3cret", with("exp", nil)), Identity{}, false},
+		{"other issuer", "Bearer " + signJWT("HS256", "s3cret", with("iss", "dev")), Identity{}, false},
+		{"other audience", "Bearer " + signJWT("HS256", "s3cret", with("aud", "billing")), Identity{}, false},
+		{"unknown role", "Bearer " + signJWT("HS256", "s3cret", with("role", "root")), Identity{}, false},
+	} {
+		r := httptest.NewRequest("GET", "/pods", nil)
+		if c.header != "" {
+			r.Header.Set("Authorization", c.header)
+		}
+		id, err := a.Authenticate(r)
+		if (err == nil) != c.ok || id != c.want {
+			t.Errorf("%s: %+v, %v", c.name, id, err)
+		}
+	}
+}
+
+func TestRequire(t *testing.T) {
+	a, _ := New(Config{Tokens: []Token{
+		{Name: "dashboard", Token: "r3ad", Role: Reader},
+		{Name: "deploy", Token: "0perate", Role: Operator},
+	}})
+	var caller Identity
+	h := a.Require(Operator, "getStatus", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ = FromContext(r.Context())
+	}))
+	for token, code := range map[string]int{"": 401, "wrong": 401, "r3ad": 403, "0perate": 200} {
+		r := httptest.NewRequest("POST", "/status/false", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Errorf("token %q: %d, want %d", token, w.Code, code)
+		}
+	}
+	if caller.Name != "deploy" {
+		t.Errorf("caller = %+v", caller)
+	}
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+// JwtConfig checks the JWTs a caller presents. A JWT must name its caller in
+// sub, its role in RoleClaim and carry exp.
+type JwtConfig struct {
+	Secret string `json:"secret"`
+	// Issuer and Audience are only checked when set.
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+	// RoleClaim defaults to role.
+	RoleClaim string `json:"role_claim"`
+	// Leeway is the clock skew in seconds allowed on exp and nbf.
+	Leeway int64 `json:"leeway"`
+}
+
+var jwtAlgorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	Expires   *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+}
+
+func (c *JwtConfig) verify(token string, now time.Time) (Identity, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Identity{}, err
+	}
+	// the algorithm is ours to pick, a token asking for none or RS256
+	// must not get checked any other way
+	newHash, ok := jwtAlgorithms[header.Alg]
+	if !ok {
+		return Identity{}, fmt.Errorf("jwt algorithm %q is not accepted", header.Alg)
+	}
+	mac := hmac.New(newHash, []byte(c.Secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return Identity{}, ErrInvalid
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Identity{}, err
+	}
+	var all map[string]interface{}
+	decodeSegment(parts[1], &all)
+	unix, leeway := now.Unix(), c.Leeway
+	switch {
+	case claims.Expires == nil:
+		return Identity{}, fmt.Errorf("jwt of %q has no exp", claims.Subject)
+	case unix > *claims.Expires+leeway:
+		return Identity{}, fmt.Errorf("jwt of %q expired", claims.Subject)
+	case claims.NotBefore != nil && unix < *claims.NotBefore-leeway:
+		return Identity{}, fmt.Errorf("jwt of %q is not valid yet", claims.Subject)
+	case c.Issuer != "" && claims.Issuer != c.Issuer:
+		return Identity{}, fmt.Errorf("jwt of %q is issued by %q", claims.Subject, claims.Issuer)
+	case c.Audience != "" && !hasAudience(claims.Audience, c.Audience):
+		return Identity{}, fmt.Errorf("jwt of %q is not meant for %q", claims.Subject, c.Audience)
+	case claims.Subject == "":
+		return Identity{}, fmt.Errorf("jwt has no sub")
+	}
+	claim := c.RoleClaim
+	if claim == "" {
+		claim = "role"
+	}
+	name, _ := all[claim].(string)
+	role, err := ParseRole(name)
+	if err != nil {
+		return Identity{}, fmt.Errorf("jwt of %q: %v", claims.Subject, err)
+	}
+	return Identity{Name: claims.Subject, Role: role, Method: "jwt"}, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrInvalid
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// hasAudience reads aud, a string or a list of them.
+func hasAudience(aud json.RawMessage, want string) bool {
+	var one string
+	if json.Unmarshal(aud, &one) == nil {
+		return one == want
+	}
+	var many []string
+	json.Unmarshal(aud, &many)
+	for _, a := range many {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+// Package certs serves TLS with a certificate, key and client CA bundle that
+// are read again when their files change, so rotating them needs no restart.
+package certs
+
+import (
+	"common"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader hands out the TLS config of the latest files that loaded.
+type Reloader struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is the bundle client certificates are verified
+	// against, empty to not ask for them.
+	ClientCAFile string
+	// ClientAuth is how client certificates are asked for when there is a
+	// bundle.
+	ClientAuth tls.ClientAuthType
+	// Every is how often the files are looked at, at most once per
+	// handshake.
+	Every time.Duration
+
+	lock    sync.Mutex
+	checked time.Time
+	stamps  []time.Time
+	config  *tls.Config
+}
+
+// New loads the files, failing when they do not make a usable config.
+func New(certFile string, keyFile string, clientCAFile string, clientAuth tls.ClientAuthType) (*Reloader, error) {
+	r := &Reloader{CertFile: certFile, KeyFile: keyFile, ClientCAFile: clientCAFile, ClientAuth: clientAuth, Every: 5 * time.Second}
+	stamps, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if r.config, err = r.load(); err != nil {
+		return nil, err
+	}
+	r.stamps, r.checked = stamps, time.Now()
+	return r, nil
+}
+
+// TLSConfig is the config to serve with.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(time.Now()), nil
+		},
+	}
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.CertFile, r.KeyFile}
+	if r.ClientCAFile != "" {
+		files = append(files, r.ClientCAFile)
+	}
+	return files
+}
+
+func (r *Reloader) stat() ([]time.Time, error) {
+	var stamps []time.Time
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, info.ModTime())
+	}
+	return stamps, nil
+}
+
+// current reloads the files when they changed. A change that does not load,
+// a key rotated before its certificate, keeps the last config until the
+// files are consistent again.
+func (r *Reloader) current(now time.Time) *tls.Config {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if now.Sub(r.checked) < r.Every {
+		return r.config
+	}
+	r.checked = now
+	stamps, err := r.stat()
+	if err != nil {
+		common.LogErr(err)
+		return r.config
+	}
+	if sameStamps(stamps, r.stamps) {
+		return r.config
+	}
+	config, err := r.load()
+	if err != nil {
+		common.LogErr(fmt.Errorf("keeping the previous certificate: %v", err))
+		return r.config
+	}
+	common.DebugPrint("reloaded the certificate from", r.CertFile)
+	r.config, r.stamps = config, stamps
+	return r.config
+}
+
+func sameStamps(a []time.Time, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Reloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	if r.ClientCAFile == "" {
+		return config, nil
+	}
+	pem, err := ioutil.ReadFile(r.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New(r.ClientCAFile + " holds no certificate")
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = r.ClientAuth
+	return config, nil
+}
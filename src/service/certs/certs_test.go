@@ -0,0 +1,139 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"service/auth"
+	"testing"
+	"time"
+)
+
+type issued struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// issue signs a certificate for cn with parent, itself when parent is nil.
+func issue(t *testing.T, cn string, serial int64, parent *issued) *issued {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &issued{cert: cert, key: key, der: der}
+}
+
+func (i *issued) write(t *testing.T, certFile string, keyFile string) {
+	keyDer, _ := x509.MarshalECPrivateKey(i.key)
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: i.der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if keyFile != "" {
+		ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	}
+}
+
+func (i *issued) pair() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{i.der}, PrivateKey: i.key}
+}
+
+func TestReloadAndClientCerts(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	ca := issue(t, "test-ca", 1, nil)
+	ca.write(t, caFile, "")
+	issue(t, "collector", 2, ca).write(t, certFile, keyFile)
+
+	r, err := New(certFile, keyFile, caFile, tls.VerifyClientCertIfGiven)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := auth.New(auth.Config{Client_certs: map[string]auth.Role{"deploy": auth.Operator}})
+	srv := httptest.NewUnstartedServer(a.Require(auth.Operator, "getStatus", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := auth.FromContext(r.Context())
+		w.Write([]byte(id.Name))
+	})))
+	srv.TLS = r.TLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(client *issued) (*http.Response, error) {
+		config := &tls.Config{RootCAs: roots}
+		if client != nil {
+			config.Certificates = []tls.Certificate{client.pair()}
+		}
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		return c.Post(srv.URL, "", nil)
+	}
+	for _, c := range []struct {
+		client *issued
+		code   int
+	}{
+		{nil, http.StatusUnauthorized},
+		{issue(t, "intruder", 3, ca), http.StatusUnauthorized},
+		{issue(t, "deploy", 4, ca), http.StatusOK},
+	} {
+		resp, err := get(c.client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Errorf("status %d, want %d", resp.StatusCode, c.code)
+		}
+	}
+	// a certificate from elsewhere is not sent or fails the handshake
+	if resp, err := get(issue(t, "deploy", 5, nil)); err == nil && resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("a self-signed client certificate was accepted: %d", resp.StatusCode)
+	}
+
+	// a rotated certificate is served from the next check on; a half
+	// rotated one keeps the previous
+	rotated := issue(t, "collector", 6, ca)
+	rotated.write(t, certFile, "")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	if got := r.current(later).Certificates[0].Leaf; got != nil && got.SerialNumber.Int64() != 2 {
+		t.Errorf("a mismatched key pair was loaded")
+	}
+	rotated.write(t, certFile, keyFile)
+	os.Chtimes(keyFile, later, later)
+	served, _ := x509.ParseCertificate(r.current(later.Add(time.Minute)).Certificates[0].Certificate[0])
+	if served.SerialNumber.Int64() != 6 {
+		t.Errorf("serving serial %d after the rotation", served.SerialNumber)
+	}
+}
@@ -0,0 +1,296 @@
+package collect
+
+import (
+	"bufio"
+	"bytes"
+	"common"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// source answers the GET requests of the collectors. It is the apiserver
+// unless a cassette is being recorded or replayed.
+type source interface {
+	get(path string, header http.Header) (*http.Response, error)
+}
+
+type httpSource struct{}
+
+func (httpSource) get(path string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest("GET", KuberMasterIp+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return kubeClient.Do(req.WithContext(runContext()))
+}
+
+var kubeSource source = httpSource{}
+
+// cycleCtx is cancelled by Abort. runCtx, derived from it while a cycle
+// runs, is cancelled by Interrupt as well.
+var cycleCtx, abortCycles = context.WithCancel(context.Background())
+var runLock sync.Mutex
+var runCtx context.Context
+var interruptRun context.CancelFunc
+
+// Abort cancels the apiserver requests of the cycles in flight, at shutdown
+// when they did not finish in time; their runs end interrupted. No cycle
+// starts afterwards.
+func Abort() {
+	abortCycles()
+}
+
+// Interrupt cancels the apiserver requests of the cycle in flight, when this
+// replica stopped leading; its run ends interrupted. The next cycle runs as
+// usual.
+func Interrupt() {
+	runLock.Lock()
+	defer runLock.Unlock()
+	if interruptRun != nil {
+		interruptRun()
+	}
+}
+
+// startRunContext starts the context of a cycle, endRunContext drops it.
+func startRunContext() {
+	runLock.Lock()
+	defer runLock.Unlock()
+	runCtx, interruptRun = context.WithCancel(cycleCtx)
+}
+
+func endRunContext() {
+	runLock.Lock()
+	defer runLock.Unlock()
+	interruptRun()
+	runCtx, interruptRun = nil, nil
+}
+
+func runContext() context.Context {
+	runLock.Lock()
+	defer runLock.Unlock()
+	if runCtx == nil {
+		return cycleCtx
+	}
+	return runCtx
+}
+
+// aborted reports whether the cycle in flight was aborted or interrupted.
+func aborted() bool {
+	return runContext().Err() != nil
+}
+
+// Close flushes the cassette being recorded.
+func Close() error {
+	if r, ok := kubeSource.(*recorder); ok {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		return r.index.Close()
+	}
+	return nil
+}
+
+// A cassette is a directory of gzipped response bodies, one file per
+// response, and index.jsonl describing them in the order they were received.
+const cassetteIndex = "index.jsonl"
+
+type cassetteEntry struct {
+	Time         string `json:"time"`
+	Path         string `json:"path"`
+	Status       int    `json:"status"`
+	Content_type string `json:"content_type"`
+	File         string `json:"file"`
+}
+
+type recorder struct {
+	next  source
+	dir   string
+	lock  sync.Mutex
+	seq   int
+	index *os.File
+}
+
+// Record saves every response the collectors receive from now on to the
+// cassette dir, appending when it already holds one.
+func Record(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	seq, err := cassetteLength(dir)
+	if err != nil {
+		return err
+	}
+	index, err := os.OpenFile(filepath.Join(dir, cassetteIndex), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	kubeSource = &recorder{next: kubeSource, dir: dir, seq: seq, index: index}
+	common.DebugPrint("recording apiserver responses to", dir)
+	return nil
+}
+
+func cassetteLength(dir string) (int, error) {
+	entries, err := readCassette(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	return len(entries), err
+}
+
+func (r *recorder) get(path string, header http.Header) (*http.Response, error) {
+	resp, err := r.next.get(path, header)
+	if err != nil {
+		return nil, err
+	}
+	entry := cassetteEntry{
+		Time:         time.Now().Format(time.RFC3339Nano),
+		Path:         path,
+		Status:       resp.StatusCode,
+		Content_type: resp.Header.Get("Content-Type"),
+	}
+	resp.Body = &recordingBody{body: resp.Body, r: r, entry: entry}
+	return resp, nil
+}
+
+// recordingBody keeps a copy of what the collector reads and saves it when
+// the body is closed; what was left unread is read then.
+type recordingBody struct {
+	body  io.ReadCloser
+	r     *recorder
+	entry cassetteEntry
+	data  bytes.Buffer
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.data.Write(p[:n])
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	io.Copy(&b.data, b.body)
+	err := b.r.save(b.entry, b.data.Bytes())
+	common.LogErr(err)
+	return b.body.Close()
+}
+
+func (r *recorder) save(entry cassetteEntry, data []byte) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.seq++
+	entry.File = fmt.Sprintf("%06d.gz", r.seq)
+	f, err := os.Create(filepath.Join(r.dir, entry.File))
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(f)
+	zw.Name = entry.Path
+	if _, err := zw.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = r.index.Write(append(line, '\n'))
+	return err
+}
+
+// replayer answers every path with the responses recorded for it, in the
+// order they were recorded, so the runs of the recording replay one by one.
+type replayer struct {
+	dir     string
+	lock    sync.Mutex
+	pending map[string][]cassetteEntry
+}
+
+// Replay makes the collectors read the cassette dir instead of the
+// apiserver. A request the cassette holds no more responses for fails.
+func Replay(dir string) error {
+	entries, err := readCassette(dir)
+	if err != nil {
+		return err
+	}
+	r := &replayer{dir: dir, pending: make(map[string][]cassetteEntry)}
+	for _, e := range entries {
+		r.pending[e.Path] = append(r.pending[e.Path], e)
+	}
+	kubeSource = r
+	common.DebugPrint("replaying", len(entries), "apiserver responses from", dir)
+	return nil
+}
+
+func readCassette(dir string) ([]cassetteEntry, error) {
+	f, err := os.Open(filepath.Join(dir, cassetteIndex))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []cassetteEntry
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		var e cassetteEntry
+		if err := json.Unmarshal(lines.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", cassetteIndex, len(entries)+1, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, lines.Err()
+}
+
+func (r *replayer) get(path string, header http.Header) (*http.Response, error) {
+	r.lock.Lock()
+	queue := r.pending[path]
+	if len(queue) == 0 {
+		r.lock.Unlock()
+		return nil, fmt.Errorf("replay: the cassette holds no more responses for %s", path)
+	}
+	e := queue[0]
+	r.pending[path] = queue[1:]
+	r.lock.Unlock()
+
+	f, err := os.Open(filepath.Join(r.dir, e.File))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	resp := &http.Response{
+		Status:        strconv.Itoa(e.Status) + " " + http.StatusText(e.Status),
+		StatusCode:    e.Status,
+		Header:        make(http.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+	}
+	if e.Content_type != "" {
+		resp.Header.Set("Content-Type", e.Content_type)
+	}
+	return resp, nil
+}
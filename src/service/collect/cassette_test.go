@@ -0,0 +1,50 @@
+package collect
+
+import (
+	"dao"
+	model "model/collect"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	srv, store := fakeCluster(t)
+	dir := t.TempDir()
+	saved := kubeSource
+	t.Cleanup(func() { kubeSource = saved })
+	if err := Record(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := Init(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	RunOneCycle()
+	srv.Close()
+
+	kubeSource = saved
+	if err := Replay(dir); err != nil {
+		t.Fatal(err)
+	}
+	replayed := dao.NewMemStore()
+	dao.DefaultStore = replayed
+	if err := Init(""); err != nil {
+		t.Fatal(err)
+	}
+	RunOneCycle()
+
+	runs := replayed.Rows("runs")
+	if len(runs) != 1 || runs[0].(*model.Runs).Status != model.RunCompleted {
+		t.Fatalf("replayed runs = %+v", runs)
+	}
+	for _, table := range []string{"pods", "nodes", "services"} {
+		if a, b := len(store.Rows(table)), len(replayed.Rows(table)); a != b || a == 0 {
+			t.Errorf("%s: recorded %d rows, replayed %d", table, a, b)
+		}
+	}
+
+	// the cassette holds one run, a second one has nothing to read
+	RunOneCycle()
+	runs = replayed.Rows("runs")
+	if len(runs) != 2 || runs[1].(*model.Runs).Status != model.RunPartial {
+		t.Errorf("runs past the cassette = %+v", runs)
+	}
+}
@@ -0,0 +1,149 @@
+package collect
+
+import (
+	"common"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/pkg/api/unversioned"
+)
+
+// VersionInfo is what the apiserver returns at /version.
+type VersionInfo struct {
+	Major      string `json:"major"`
+	Minor      string `json:"minor"`
+	GitVersion string `json:"gitVersion"`
+	Platform   string `json:"platform"`
+}
+
+// Discovery holds the group versions and resources the cluster serves.
+type Discovery struct {
+	Version VersionInfo `json:"version"`
+	// Resources maps every served group version, "v1" for the core group,
+	// to the resources it serves.
+	Resources map[string][]string `json:"resources"`
+	// Failed maps the group versions whose resources could not be listed
+	// to the error; their resources are the ones discovered before.
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+var (
+	discoveryLock sync.Mutex
+	discovered    *Discovery
+)
+
+// Init points the collector at master and discovers what it serves. When
+// discovery fails the collectors fall back to their oldest group version and
+// discovery is retried at the start of the next run.
+func Init(master string) error {
+	if master != "" {
+		KuberMasterIp = master
+	}
+	_, err := refreshDiscovery()
+	return err
+}
+
+// Discover queries /version, /api and /apis and the resource list of every
+// group version found. A group version whose list fails keeps its resources
+// of previous, which may be nil.
+func Discover(previous *Discovery) (*Discovery, error) {
+	d := &Discovery{Resources: make(map[string][]string)}
+	if err := GainResourceFromK8s(&d.Version, "/version"); err != nil {
+		return nil, err
+	}
+	var core unversioned.APIVersions
+	if err := GainResourceFromK8s(&core, "/api"); err != nil {
+		return nil, err
+	}
+	var groups unversioned.APIGroupList
+	if err := GainResourceFromK8s(&groups, "/apis"); err != nil {
+		return nil, err
+	}
+	groupVersions := append([]string{}, core.Versions...)
+	for _, g := range groups.Groups {
+		for _, v := range g.Versions {
+			groupVersions = append(groupVersions, v.GroupVersion)
+		}
+	}
+	for _, gv := range groupVersions {
+		var resources unversioned.APIResourceList
+		if err := GainResourceFromK8s(&resources, groupVersionPath(gv)); err != nil {
+			// a broken aggregated API must not hide the rest, nor a
+			// timeout the resources it served until now
+			if d.Failed == nil {
+				d.Failed = make(map[string]string)
+			}
+			d.Failed[gv] = err.Error()
+			if previous != nil && previous.Resources[gv] != nil {
+				d.Resources[gv] = previous.Resources[gv]
+			}
+			continue
+		}
+		for _, r := range resources.APIResources {
+			d.Resources[gv] = append(d.Resources[gv], r.Name)
+		}
+	}
+	return d, nil
+}
+
+func refreshDiscovery() (*Discovery, error) {
+	d, err := Discover(CurrentDiscovery())
+	discoveryLock.Lock()
+	defer discoveryLock.Unlock()
+	if err != nil {
+		KuberMasterStatus = false
+		return discovered, err
+	}
+	KuberMasterStatus = true
+	discovered = d
+	common.DebugPrint("discovered kubernetes", d.Version.GitVersion, "serving", len(d.Resources), "group versions")
+	for gv, failure := range d.Failed {
+		common.DebugPrint("discovery of", gv, "failed:", failure)
+	}
+	return d, nil
+}
+
+// CurrentDiscovery returns the latest successful discovery, nil if there was
+// none yet.
+func CurrentDiscovery() *Discovery {
+	discoveryLock.Lock()
+	defer discoveryLock.Unlock()
+	return discovered
+}
+
+// Serves reports whether group version gv serves resource.
+func (d *Discovery) Serves(gv string, resource string) bool {
+	for _, r := range d.Resources[gv] {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// groupVersionPath returns the root path of a group version, "v1" being the
+// legacy core group under /api.
+func groupVersionPath(gv string) string {
+	if !strings.Contains(gv, "/") {
+		return "/api/" + gv
+	}
+	return "/apis/" + gv
+}
+
+// resourcePath picks the first of the preferred group versions the cluster
+// serves resource in. Without discovery the last, oldest one is assumed, as
+// that is what the clusters this collector started with serve.
+func resourcePath(d *Discovery, preferred []string, resource string) (string, bool) {
+	if len(preferred) == 0 {
+		return "", false
+	}
+	if d == nil {
+		return groupVersionPath(preferred[len(preferred)-1]) + "/" + resource, true
+	}
+	for _, gv := range preferred {
+		if d.Serves(gv, resource) {
+			return groupVersionPath(gv) + "/" + resource, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,48 @@
+package collect
+
+import (
+	"service/collect/fakeapi"
+	"testing"
+)
+
+func TestResourcePath(t *testing.T) {
+	srv, _ := fakeCluster(t)
+	srv.Add("extensions/v1beta1", "deployments", "Deployment")
+	srv.Add("apps/v1beta1", "deployments", "Deployment")
+	srv.Add("apps/v1beta1", "statefulsets", "StatefulSet")
+	preferred := []string{"apps/v1", "apps/v1beta1", "extensions/v1beta1"}
+
+	d, err := refreshDiscovery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Serves("v1", "pods") || !d.Serves("apps/v1beta1", "statefulsets") || d.Serves("apps/v1", "deployments") {
+		t.Errorf("resources = %v", d.Resources)
+	}
+	if p, ok := resourcePath(d, preferred, "deployments"); !ok || p != "/apis/apps/v1beta1/deployments" {
+		t.Errorf("deployments at %q, %v", p, ok)
+	}
+	if _, ok := resourcePath(d, []string{"batch/v1"}, "jobs"); ok {
+		t.Error("jobs are not served")
+	}
+	if p, ok := resourcePath(nil, preferred, "deployments"); !ok || p != "/apis/extensions/v1beta1/deployments" {
+		t.Errorf("deployments without discovery at %q, %v", p, ok)
+	}
+
+	// a group version that fails to list keeps what it served
+	srv.Fail("/apis/apps/v1beta1", fakeapi.InternalError)
+	d, err = refreshDiscovery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Failed["apps/v1beta1"] == "" || len(d.Failed) != 1 {
+		t.Errorf("failed = %v", d.Failed)
+	}
+	if p, ok := resourcePath(d, preferred, "deployments"); !ok || p != "/apis/apps/v1beta1/deployments" {
+		t.Errorf("deployments at %q, %v after a failed discovery", p, ok)
+	}
+	d, err = refreshDiscovery()
+	if err != nil || len(d.Failed) != 0 {
+		t.Errorf("failed = %v, %v", d.Failed, err)
+	}
+}
@@ -0,0 +1,89 @@
+package fakeapi
+
+import (
+	model "model/collect"
+	"time"
+
+	"k8s.io/client-go/pkg/api/resource"
+	"k8s.io/client-go/pkg/api/unversioned"
+	"k8s.io/client-go/pkg/types"
+)
+
+// Created is the creation time of every fixture.
+var Created = unversioned.NewTime(time.Date(2017, 3, 1, 10, 0, 0, 0, time.Local))
+
+func meta(namespace string, name string) model.ObjectMeta {
+	return model.ObjectMeta{
+		Namespace:         namespace,
+		Name:              name,
+		UID:               types.UID(namespace + "/" + name),
+		CreationTimestamp: Created,
+		Labels:            map[string]string{"app": name},
+	}
+}
+
+// Pod is a running pod of one container scheduled on node.
+func Pod(namespace string, name string, node string) *model.Pod {
+	pod := &model.Pod{ObjectMeta: meta(namespace, name)}
+	pod.Kind, pod.APIVersion = "Pod", "v1"
+	pod.Spec.NodeName = node
+	pod.Spec.RestartPolicy = model.RestartPolicyAlways
+	pod.Spec.Containers = []model.Container{{Name: name, Image: "nginx:1.11"}}
+	pod.Status.Phase = model.PodRunning
+	pod.Status.HostIP = "10.0.0.1"
+	pod.Status.PodIP = "10.1.0.1"
+	start := Created
+	pod.Status.StartTime = &start
+	pod.Status.ContainerStatuses = []model.ContainerStatus{{Name: name, Ready: true}}
+	return pod
+}
+
+// Node is a ready node with 4 cores, 8Gi of memory and room for 110 pods.
+func Node(name string) *model.Node {
+	node := &model.Node{ObjectMeta: meta("", name)}
+	node.Kind, node.APIVersion = "Node", "v1"
+	capacity := model.ResourceList{
+		model.ResourceCPU:    resource.MustParse("4"),
+		model.ResourceMemory: resource.MustParse("8Gi"),
+		model.ResourcePods:   resource.MustParse("110"),
+	}
+	node.Status.Capacity = capacity
+	node.Status.Allocatable = capacity
+	node.Status.Conditions = []model.NodeCondition{{Type: model.NodeReady, Status: model.ConditionTrue}}
+	node.Status.NodeInfo.KubeletVersion = "v1.5.2"
+	node.Status.Addresses = []model.NodeAddress{{Type: model.NodeInternalIP, Address: "10.0.0.1"}}
+	return node
+}
+
+// Service is a ClusterIP service selecting the pods labelled app=name.
+func Service(namespace string, name string) *model.Service {
+	service := &model.Service{ObjectMeta: meta(namespace, name)}
+	service.Kind, service.APIVersion = "Service", "v1"
+	service.Spec.Type = model.ServiceTypeClusterIP
+	service.Spec.ClusterIP = "10.254.0.1"
+	service.Spec.Selector = map[string]string{"app": name}
+	service.Spec.Ports = []model.ServicePort{{Name: "http", Protocol: model.ProtocolTCP, Port: 80}}
+	return service
+}
+
+// Event is a warning about the object kind namespace/name.
+func Event(namespace string, name string, kind string, reason string) *model.Event {
+	event := &model.Event{ObjectMeta: meta(namespace, name+"."+reason)}
+	event.Kind, event.APIVersion = "Event", "v1"
+	event.InvolvedObject = model.ObjectReference{Kind: kind, Namespace: namespace, Name: name, UID: types.UID(namespace + "/" + name)}
+	event.Reason = reason
+	event.Type = "Warning"
+	event.Count = 1
+	event.FirstTimestamp = Created
+	event.LastTimestamp = Created
+	event.Source = model.EventSource{Component: "kubelet"}
+	return event
+}
+
+// Namespace is an active namespace.
+func Namespace(name string) *model.Namespace {
+	namespace := &model.Namespace{ObjectMeta: meta("", name)}
+	namespace.Kind, namespace.APIVersion = "Namespace", "v1"
+	namespace.Status.Phase = model.NamespaceActive
+	return namespace
+}
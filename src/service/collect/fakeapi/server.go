@@ -0,0 +1,409 @@
+// Package fakeapi is an in-process stand-in for the Kubernetes apiserver that
+// the collector tests run against. It serves discovery, lists with paging
+// and watch streams of the objects added to it, and fails requests on demand.
+package fakeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/pkg/api/unversioned"
+)
+
+// Fault is a failure injected into the next request of a path.
+type Fault int
+
+const (
+	// Pass lets the request through, to fail a later one.
+	Pass Fault = iota
+	// Timeout holds the request until the client gives up.
+	Timeout
+	// InternalError answers 500.
+	InternalError
+	// Gone answers 410 with an Expired status, as for a stale continue token.
+	Gone
+	// Malformed answers 200 with a truncated JSON body.
+	Malformed
+)
+
+// VersionInfo is what /version answers.
+type VersionInfo struct {
+	Major      string `json:"major"`
+	Minor      string `json:"minor"`
+	GitVersion string `json:"gitVersion"`
+	Platform   string `json:"platform"`
+}
+
+// resourceList is one listable resource, its items in list order.
+type resourceList struct {
+	groupVersion string
+	resource     string
+	kind         string
+	items        []interface{}
+}
+
+type watchEvent struct {
+	Type   string      `json:"type"`
+	Object interface{} `json:"object"`
+}
+
+type listMeta struct {
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	Continue        string `json:"continue,omitempty"`
+}
+
+type list struct {
+	Kind       string        `json:"kind"`
+	APIVersion string        `json:"apiVersion"`
+	Metadata   listMeta      `json:"metadata"`
+	Items      []interface{} `json:"items"`
+}
+
+// Server is the fake apiserver; URL is where it listens.
+type Server struct {
+	*httptest.Server
+	Version VersionInfo
+
+	lock     sync.Mutex
+	lists    map[string]*resourceList
+	faults   map[string][]Fault
+	requests map[string]int
+	watchers map[string][]chan watchEvent
+	revision int
+	closed   chan struct{}
+}
+
+// New starts a server with nothing to list. Close stops it.
+func New() *Server {
+	s := &Server{
+		Version:  VersionInfo{Major: "1", Minor: "5", GitVersion: "v1.5.2-fake", Platform: "linux/amd64"},
+		lists:    make(map[string]*resourceList),
+		faults:   make(map[string][]Fault),
+		requests: make(map[string]int),
+		watchers: make(map[string][]chan watchEvent),
+		closed:   make(chan struct{}),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Close ends the watch streams and the pending timeouts and stops the
+// server.
+func (s *Server) Close() {
+	s.lock.Lock()
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+	s.lock.Unlock()
+	s.Server.CloseClientConnections()
+	s.Server.Close()
+}
+
+// ListPath is the path a resource is listed at, "v1" being the core group.
+func ListPath(groupVersion string, resource string) string {
+	if groupVersion == "v1" {
+		return "/api/v1/" + resource
+	}
+	return "/apis/" + groupVersion + "/" + resource
+}
+
+// Add serves items, model/collect objects of kind, under resource of
+// groupVersion. Adding to a resource already served appends to its list and
+// sends an ADDED event to its watchers.
+func (s *Server) Add(groupVersion string, resource string, kind string, items ...interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	path := ListPath(groupVersion, resource)
+	l := s.lists[path]
+	if l == nil {
+		l = &resourceList{groupVersion: groupVersion, resource: resource, kind: kind}
+		s.lists[path] = l
+	}
+	for _, item := range items {
+		l.items = append(l.items, item)
+		s.notify(path, "ADDED", item)
+	}
+}
+
+// Remove drops the items of resource for which drop returns true and sends
+// a DELETED event for each.
+func (s *Server) Remove(groupVersion string, resource string, drop func(item interface{}) bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	path := ListPath(groupVersion, resource)
+	l := s.lists[path]
+	if l == nil {
+		return
+	}
+	kept := l.items[:0]
+	for _, item := range l.items {
+		if drop(item) {
+			s.notify(path, "DELETED", item)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	l.items = kept
+}
+
+// Emit sends an event of type to the watchers of resource without changing
+// its list.
+func (s *Server) Emit(groupVersion string, resource string, eventType string, object interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.notify(ListPath(groupVersion, resource), eventType, object)
+}
+
+func (s *Server) notify(path string, eventType string, object interface{}) {
+	s.revision++
+	for _, w := range s.watchers[path] {
+		select {
+		case w <- watchEvent{Type: eventType, Object: object}:
+		default:
+			// a watcher that does not keep up misses events, as with a
+			// real apiserver it has to relist
+		}
+	}
+}
+
+// Fail makes the next requests of path, without query, fail with faults, one
+// fault per request: Fail(path, Pass, Gone) fails the second page of a list.
+func (s *Server) Fail(path string, faults ...Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults[path] = append(s.faults[path], faults...)
+}
+
+// Requests is the number of requests path received.
+func (s *Server) Requests(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests[path]
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	s.lock.Lock()
+	s.requests[path]++
+	var fault Fault
+	if faults := s.faults[path]; len(faults) > 0 {
+		fault, s.faults[path] = faults[0], faults[1:]
+	}
+	s.lock.Unlock()
+
+	switch fault {
+	case Timeout:
+		select {
+		case <-r.Context().Done():
+		case <-s.closed:
+		}
+		return
+	case InternalError:
+		writeStatus(w, http.StatusInternalServerError, "InternalError", "injected failure")
+		return
+	case Gone:
+		writeStatus(w, http.StatusGone, "Expired", "the provided continue parameter is too old")
+		return
+	case Malformed:
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"kind": "List", "items": [{"metadata": {"name": `)
+		return
+	}
+
+	switch {
+	case path == "/version":
+		writeJSON(w, s.Version)
+	case path == "/api":
+		writeJSON(w, unversioned.APIVersions{Versions: []string{"v1"}})
+	case path == "/apis":
+		writeJSON(w, s.groups())
+	case path == "/api/v1" || strings.Count(path, "/") == 3 && strings.HasPrefix(path, "/apis/"):
+		writeJSON(w, s.resources(strings.TrimPrefix(strings.TrimPrefix(path, "/apis/"), "/api/")))
+	default:
+		s.serveResource(w, r, path)
+	}
+}
+
+func (s *Server) serveResource(w http.ResponseWriter, r *http.Request, path string) {
+	watch := r.URL.Query().Get("watch") == "true" || r.URL.Query().Get("watch") == "1"
+	if strings.Contains(path, "/watch/") {
+		path = strings.Replace(path, "/watch/", "/", 1)
+		watch = true
+	}
+	namespace := ""
+	if m := namespacedPath.FindStringSubmatch(path); m != nil && s.listed(m[1]+"/"+m[3]) {
+		path, namespace = m[1]+"/"+m[3], m[2]
+	}
+	s.lock.Lock()
+	l := s.lists[path]
+	if l == nil {
+		s.lock.Unlock()
+		writeStatus(w, http.StatusNotFound, "NotFound", "the server could not find the requested resource")
+		return
+	}
+	if watch {
+		events := make(chan watchEvent, 100)
+		s.watchers[path] = append(s.watchers[path], events)
+		s.lock.Unlock()
+		s.serveWatch(w, r, path, events)
+		return
+	}
+	var items []interface{}
+	for _, item := range l.items {
+		if namespace == "" || namespaceOf(item) == namespace {
+			items = append(items, item)
+		}
+	}
+	page := list{Kind: l.kind + "List", APIVersion: l.groupVersion, Metadata: listMeta{ResourceVersion: strconv.Itoa(s.revision)}}
+	s.lock.Unlock()
+
+	start, _ := strconv.Atoi(r.URL.Query().Get("continue"))
+	if start > len(items) {
+		start = len(items)
+	}
+	end := len(items)
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 && start+limit < end {
+		end = start + limit
+		page.Metadata.Continue = strconv.Itoa(end)
+	}
+	page.Items = items[start:end]
+	writeJSON(w, page)
+}
+
+// serveWatch streams events as newline separated JSON until the client goes
+// away or the server is closed.
+func (s *Server) serveWatch(w http.ResponseWriter, r *http.Request, path string, events chan watchEvent) {
+	defer func() {
+		s.lock.Lock()
+		watchers := s.watchers[path]
+		for i, c := range watchers {
+			if c == events {
+				s.watchers[path] = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+		s.lock.Unlock()
+	}()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case e := <-events:
+			if err := enc.Encode(e); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// namespacedPath matches the list path of the objects of one namespace.
+var namespacedPath = regexp.MustCompile(`^(/api/v1|/apis/[^/]+/[^/]+)/namespaces/([^/]+)/([^/]+)$`)
+
+func (s *Server) listed(path string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lists[path] != nil
+}
+
+func namespaceOf(item interface{}) string {
+	var obj struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+	data, _ := json.Marshal(item)
+	json.Unmarshal(data, &obj)
+	return obj.Metadata.Namespace
+}
+
+func (s *Server) groups() unversioned.APIGroupList {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	versions := make(map[string][]string)
+	for _, l := range s.lists {
+		if l.groupVersion == "v1" {
+			continue
+		}
+		group := strings.Split(l.groupVersion, "/")[0]
+		if !contains(versions[group], l.groupVersion) {
+			versions[group] = append(versions[group], l.groupVersion)
+		}
+	}
+	var names []string
+	for group := range versions {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+	groups := unversioned.APIGroupList{}
+	for _, name := range names {
+		g := unversioned.APIGroup{Name: name}
+		sort.Strings(versions[name])
+		for _, gv := range versions[name] {
+			g.Versions = append(g.Versions, unversioned.GroupVersionForDiscovery{GroupVersion: gv, Version: strings.Split(gv, "/")[1]})
+		}
+		groups.Groups = append(groups.Groups, g)
+	}
+	return groups
+}
+
+func (s *Server) resources(groupVersion string) unversioned.APIResourceList {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	resources := unversioned.APIResourceList{GroupVersion: groupVersion}
+	for _, l := range s.lists {
+		if l.groupVersion == groupVersion {
+			resources.APIResources = append(resources.APIResources, unversioned.APIResource{Name: l.resource, Kind: l.kind, Namespaced: true})
+		}
+	}
+	sort.Slice(resources.APIResources, func(i, j int) bool {
+		return resources.APIResources[i].Name < resources.APIResources[j].Name
+	})
+	return resources
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeStatus(w http.ResponseWriter, code int, reason string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(unversioned.Status{
+		TypeMeta: unversioned.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   unversioned.StatusFailure,
+		Message:  message,
+		Reason:   unversioned.StatusReason(reason),
+		Code:     int32(code),
+	})
+}
@@ -0,0 +1,40 @@
+package fakeapi
+
+import (
+	"bufio"
+	"encoding/json"
+	model "model/collect"
+	"net/http"
+	"testing"
+)
+
+func TestWatch(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+	srv.Add("v1", "pods", "Pod", Pod("default", "web-1", "node-1"))
+
+	resp, err := http.Get(srv.URL + "/api/v1/pods?watch=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	srv.Add("v1", "pods", "Pod", Pod("default", "web-2", "node-1"))
+	srv.Remove("v1", "pods", func(item interface{}) bool { return item.(*model.Pod).Name == "web-1" })
+
+	lines := bufio.NewScanner(resp.Body)
+	for _, want := range []string{"ADDED web-2", "DELETED web-1"} {
+		if !lines.Scan() {
+			t.Fatal("watch ended early:", lines.Err())
+		}
+		var e struct {
+			Type   string
+			Object model.Pod
+		}
+		if err := json.Unmarshal(lines.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		if got := e.Type + " " + e.Object.Name; got != want {
+			t.Errorf("event = %s, want %s", got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package collect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	model "model/collect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// GenericResource configures the collection of a kind without a typed
+// model, such as a custom resource. Fields maps column names to JSONPath
+// expressions whose results are stored for querying. ObjectKind, Ingress for
+// ingresses, is what an import matches the items of a dump on.
+type GenericResource struct {
+	Kind       string            `json:"kind"`
+	Path       string            `json:"path"`
+	ObjectKind string            `json:"objectKind"`
+	Fields     map[string]string `json:"fields"`
+}
+
+// maxFieldValue is the size of GenericFields.Value.
+const maxFieldValue = 255
+
+// LoadGenericResources reads a JSON array of GenericResource from file and
+// registers a collector for each.
+func LoadGenericResources(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var resources []GenericResource
+	if err := json.Unmarshal(data, &resources); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	for _, r := range resources {
+		if err := RegisterGeneric(r); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// RegisterGeneric registers a collector storing the objects listed at
+// r.Path as JSON documents.
+func RegisterGeneric(r GenericResource) error {
+	gv, resource, err := splitResourcePath(r.Path)
+	if err != nil {
+		return err
+	}
+	if r.Kind == "" {
+		r.Kind = resource
+	}
+	paths := make(map[string]*jsonPath, len(r.Fields))
+	for field, expr := range r.Fields {
+		p, err := compileJSONPath(expr)
+		if err != nil {
+			return fmt.Errorf("%s field %s: %v", r.Kind, field, err)
+		}
+		paths[field] = p
+	}
+	Register(&Collector{
+		Kind:          r.Kind,
+		Resource:      resource,
+		ObjectKind:    r.ObjectKind,
+		GroupVersions: []string{gv},
+		Enabled:       true,
+		NewItem:       func() interface{} { return new(json.RawMessage) },
+		JSONOnly:      true,
+		Store:         genericStore(r.Kind, paths),
+	})
+	return nil
+}
+
+// splitResourcePath splits /api/v1/foo or /apis/group/version/foo into the
+// group version and the resource.
+func splitResourcePath(path string) (string, string, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) == 3 && parts[0] == "api":
+		return parts[1], parts[2], nil
+	case len(parts) == 4 && parts[0] == "apis":
+		return parts[1] + "/" + parts[2], parts[3], nil
+	}
+	return "", "", fmt.Errorf("path %q is neither /api/<version>/<resource> nor /apis/<group>/<version>/<resource>", path)
+}
+
+// genericStore returns the Store of a generic kind: it keeps the document and
+// the values of the sorted fields.
+func genericStore(kind string, paths map[string]*jsonPath) func(interface{}, *Listing) error {
+	fields := make([]string, 0, len(paths))
+	for field := range paths {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return func(item interface{}, l *Listing) error {
+		raw := *item.(*json.RawMessage)
+		var object struct {
+			Metadata model.ObjectMeta `json:"metadata"`
+		}
+		var doc interface{}
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return err
+		}
+		json.Unmarshal(raw, &doc)
+		meta := object.Metadata
+		var row model.GenericObjects
+		row.Kind = kind
+		row.Namespace = meta.Namespace
+		row.Name = meta.Name
+		row.Uid = string(meta.UID)
+		row.Resource_version = meta.ResourceVersion
+		row.Labels = formatLabels(meta.Labels)
+		row.Document = string(raw)
+		row.Record_time = l.recordTime()
+		row.Tag = l.Run.Tag
+		if err := insertRow(&row); err != nil {
+			return err
+		}
+		for _, field := range fields {
+			var value model.GenericFields
+			value.Kind = kind
+			value.Uid = row.Uid
+			value.Field = field
+			value.Value = truncate(paths[field].String(doc), maxFieldValue)
+			value.Record_time = row.Record_time
+			value.Tag = l.Run.Tag
+			if err := insertRow(&value); err != nil {
+				return err
+			}
+		}
+		if err := insertLabels(kind, meta, l); err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+// truncate cuts s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,84 @@
+package collect
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// running counts the cycles in flight, progressed is when one of them last
+// got an answer from the apiserver.
+var (
+	progressLock sync.Mutex
+	running      int
+	progressed   time.Time
+)
+
+func cycleStarted() {
+	progressLock.Lock()
+	defer progressLock.Unlock()
+	running++
+	progressed = time.Now()
+}
+
+func cycleFinished() {
+	progressLock.Lock()
+	defer progressLock.Unlock()
+	running--
+}
+
+func progress() {
+	progressLock.Lock()
+	defer progressLock.Unlock()
+	progressed = time.Now()
+}
+
+// Stalled fails when cycles are in flight but none of them got an answer
+// from the apiserver for longer than after.
+func Stalled(after time.Duration) error {
+	progressLock.Lock()
+	defer progressLock.Unlock()
+	if running == 0 {
+		return nil
+	}
+	if since := time.Since(progressed); since > after {
+		return fmt.Errorf("%d cycles in flight made no progress for %s", running, since.Truncate(time.Second))
+	}
+	return nil
+}
+
+// pingTimeout is shorter than the timeout of the collectors, a probe waits
+// for the ping.
+var pingTimeout = 5 * time.Second
+
+// Ping asks the apiserver for its version and refreshes KuberMasterStatus.
+// While a cassette is replayed there is no apiserver to ask.
+func Ping() (string, error) {
+	if r, ok := kubeSource.(*replayer); ok {
+		return "replaying " + r.dir, nil
+	}
+	v, err := pingVersion()
+	discoveryLock.Lock()
+	KuberMasterStatus = err == nil
+	discoveryLock.Unlock()
+	if err != nil {
+		return "", err
+	}
+	return KuberMasterIp + " serves " + v.GitVersion, nil
+}
+
+func pingVersion() (VersionInfo, error) {
+	var v VersionInfo
+	client := &http.Client{Timeout: pingTimeout, Transport: kubeClient.Transport}
+	resp, err := client.Get(KuberMasterIp + "/version")
+	if err != nil {
+		return v, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return v, fmt.Errorf("get /version: %s", resp.Status)
+	}
+	return v, json.NewDecoder(resp.Body).Decode(&v)
+}
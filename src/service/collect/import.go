@@ -0,0 +1,136 @@
+package collect
+
+import (
+	"common"
+	"encoding/json"
+	"fmt"
+	"io"
+	model "model/collect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Importer writes the lists of kubectl dumps, `kubectl get -o json` output,
+// as one collection run through the same Store functions as the collectors.
+// The inventory is left alone, a dump may come from any cluster.
+type Importer struct {
+	cluster  string
+	taken    time.Time
+	run      *model.Runs
+	listings map[string]*Listing
+	failed   map[string]error
+	// Skipped counts the items of kinds no collector stores.
+	Skipped map[string]int
+}
+
+func NewImporter(cluster string) *Importer {
+	return &Importer{
+		cluster:  cluster,
+		listings: make(map[string]*Listing),
+		failed:   make(map[string]error),
+		Skipped:  make(map[string]int),
+	}
+}
+
+// collectorForObject finds the collector of objects of kind by its
+// ObjectKind, or by its kind for a generic collector that was given none.
+func collectorForObject(kind string) *Collector {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for _, c := range collectors {
+		if c.ObjectKind == kind || c.ObjectKind == "" && strings.EqualFold(c.Kind, kind) {
+			copied := *c
+			return &copied
+		}
+	}
+	return nil
+}
+
+func (im *Importer) clock() time.Time {
+	return im.taken
+}
+
+// Read imports the list r holds, name is only used in messages. The run is
+// started with the time taken of the first list read.
+func (im *Importer) Read(r io.Reader, name string, taken time.Time) error {
+	if im.run == nil {
+		im.taken = taken
+		im.run = startRun(nil, im.cluster, "", taken)
+		if im.run.Id == 0 {
+			return fmt.Errorf("import %s: the run could not be stored", name)
+		}
+	}
+	_, err := decodeList(r, func() interface{} { return new(json.RawMessage) }, func(item interface{}) error {
+		raw := *item.(*json.RawMessage)
+		var head struct {
+			Kind string `json:"kind"`
+		}
+		json.Unmarshal(raw, &head)
+		c := collectorForObject(head.Kind)
+		if c == nil {
+			im.Skipped[head.Kind]++
+			return nil
+		}
+		l := im.listings[c.Kind]
+		if l == nil {
+			l = &Listing{Run: im.run, Kind: c.Kind, clock: im.clock}
+			im.listings[c.Kind] = l
+		}
+		obj := c.NewItem()
+		if err := decodeTolerant(raw, obj, name); err != nil {
+			return err
+		}
+		l.Items++
+		if err := c.Store(obj, l); err != nil && im.failed[c.Kind] == nil {
+			im.failed[c.Kind] = err
+		}
+		return nil
+	}, name)
+	if err != nil {
+		return fmt.Errorf("import %s: %v", name, err)
+	}
+	return nil
+}
+
+// Finish runs the Finish hooks of the kinds read and closes the run, which
+// is nil when nothing was read.
+func (im *Importer) Finish() (*model.Runs, []model.RunResults) {
+	if im.run == nil {
+		return nil, nil
+	}
+	var kinds []string
+	for kind := range im.listings {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	var results []model.RunResults
+	for _, kind := range kinds {
+		l := im.listings[kind]
+		result := model.RunResults{Run_id: im.run.Id, Tag: im.run.Tag, Kind: kind, Items: l.Items, Encoding: "import"}
+		err := im.failed[kind]
+		if c := collectorByKind(kind); err == nil && c != nil && c.Finish != nil {
+			err = c.Finish(l)
+		}
+		if err != nil {
+			common.LogErr(err)
+			result.Status = model.CollectorFailed
+			result.Error = err.Error()
+		} else {
+			result.Status = model.CollectorOk
+		}
+		results = append(results, result)
+	}
+	finishRun(im.run, results, im.taken)
+	return im.run, results
+}
+
+func collectorByKind(kind string) *Collector {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if c := findCollector(kind); c != nil {
+		copied := *c
+		return &copied
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package collect
+
+import (
+	"bytes"
+	"common"
+	"dao"
+	"encoding/json"
+	model "model/collect"
+	"service/collect/fakeapi"
+	"testing"
+	"time"
+)
+
+func TestImport(t *testing.T) {
+	store := dao.NewMemStore()
+	saved := dao.DefaultStore
+	dao.DefaultStore = store
+	defer func() { dao.DefaultStore = saved }()
+	registered := append([]*Collector(nil), collectors...)
+	defer func() { collectors = registered }()
+	if err := RegisterGeneric(GenericResource{Kind: "ingresses", ObjectKind: "Ingress", Path: "/apis/extensions/v1beta1/ingresses"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterGeneric(GenericResource{Kind: "NetworkPolicy", Path: "/apis/extensions/v1beta1/networkpolicies"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dump, err := json.Marshal(map[string]interface{}{
+		"kind":       "List",
+		"apiVersion": "v1",
+		"items": []interface{}{
+			fakeapi.Pod("default", "web-1", "node-1"),
+			fakeapi.Pod("default", "web-2", "node-1"),
+			fakeapi.Node("node-1"),
+			fakeapi.Service("default", "web"),
+			map[string]interface{}{"kind": "Ingress", "metadata": map[string]string{"name": "web", "uid": "ingress-uid"}},
+			map[string]interface{}{"kind": "NetworkPolicy", "metadata": map[string]string{"name": "deny-all"}},
+			map[string]interface{}{"kind": "ConfigMap", "metadata": map[string]string{"name": "settings"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken := time.Date(2017, 6, 1, 8, 30, 0, 0, time.Local)
+	im := NewImporter("air-gapped-1")
+	if err := im.Read(bytes.NewReader(dump), "dump.json", taken); err != nil {
+		t.Fatal(err)
+	}
+	run, results := im.Finish()
+
+	if run == nil || run.Cluster != "air-gapped-1" || run.Status != model.RunCompleted ||
+		run.Start_time != common.RecordTime(taken) || run.End_time != run.Start_time {
+		t.Fatalf("run = %+v", run)
+	}
+	if len(results) != 5 {
+		t.Errorf("results = %+v", results)
+	}
+	pods := store.Rows("pods")
+	if len(pods) != 2 {
+		t.Fatalf("%d pods stored", len(pods))
+	}
+	for _, row := range pods {
+		p := row.(*model.Pods)
+		if p.Tag != run.Tag || p.Record_time != run.Start_time || p.All_pod_numbers != "2" {
+			t.Errorf("pod = %+v", p)
+		}
+	}
+	if len(store.Rows("nodes")) != 1 || len(store.Rows("services")) != 1 {
+		t.Errorf("nodes %d, services %d", len(store.Rows("nodes")), len(store.Rows("services")))
+	}
+	if im.Skipped["ConfigMap"] != 1 || len(im.Skipped) != 1 {
+		t.Errorf("skipped = %v", im.Skipped)
+	}
+	var generic []string
+	for _, row := range store.Rows("generic_objects") {
+		generic = append(generic, row.(*model.GenericObjects).Kind)
+	}
+	if len(generic) != 2 || generic[0] != "ingresses" || generic[1] != "NetworkPolicy" {
+		t.Errorf("generic objects of %v", generic)
+	}
+	if rows := store.Inventory("pod_inventory"); len(rows) != 0 {
+		t.Errorf("an import touched the inventory: %+v", rows)
+	}
+}
+
+func TestImportDuringCycle(t *testing.T) {
+	_, store := fakeCluster(t)
+	dump, err := json.Marshal(map[string]interface{}{"kind": "List", "items": []interface{}{fakeapi.Pod("default", "old", "node-1")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken := time.Date(2017, 6, 1, 8, 30, 0, 0, time.Local)
+	done := make(chan struct{})
+	go func() {
+		RunOneCycle()
+		close(done)
+	}()
+	im := NewImporter("air-gapped-1")
+	if err := im.Read(bytes.NewReader(dump), "dump.json", taken); err != nil {
+		t.Fatal(err)
+	}
+	im.Finish()
+	<-done
+
+	// the time of the dump is only written to the rows of the import
+	for _, row := range store.Rows("pods") {
+		if p := row.(*model.Pods); (p.Pod_name == "old") != (p.Record_time == common.RecordTime(taken)) {
+			t.Errorf("pod %s recorded at %s", p.Pod_name, p.Record_time)
+		}
+	}
+}
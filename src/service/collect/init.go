@@ -1,34 +1,92 @@
 package collect
 
 import (
+	"common"
+	"dao"
 	model "model/collect"
 	"sync"
+	"time"
 )
 
 var ThreadCountGet sync.WaitGroup
 
+// ClusterName is recorded with every run.
+var ClusterName string
+
+// Leading reports whether this replica may collect. It is checked once the
+// cycle can be interrupted, a replica stepping down later interrupts it.
+var Leading = func() bool { return true }
+
 func RunOneCycle() error {
-	var a GainKubernetes = new(KubernetesAllResource)
-	gainResource(a)
-	ThreadCountGet.Wait()
+	if cycleCtx.Err() != nil {
+		return nil
+	}
+	startRunContext()
+	defer endRunContext()
+	if !Leading() {
+		return nil
+	}
+	cycleStarted()
+	defer cycleFinished()
+	d := CurrentDiscovery()
+	if d == nil {
+		d, _ = refreshDiscovery()
+	}
+	shard := ""
+	if CurrentShard != nil {
+		shard = CurrentShard.Describe()
+	}
+	run := startRun(d, ClusterName, shard, time.Now())
+	results := collectAll(run, d)
+	finishRun(run, results, time.Now())
 	return nil
 }
 
-type KubernetesAllResource struct {
-	nodes    model.Nodes
-	pods     model.Pods
-	services model.Services
+func startRun(d *Discovery, cluster string, shard string, start time.Time) *model.Runs {
+	run := &model.Runs{
+		Tag:        common.Gen_id(5),
+		Cluster:    cluster,
+		Shard:      shard,
+		Start_time: common.RecordTime(start),
+		Status:     model.RunRunning,
+	}
+	if d != nil {
+		run.Server_version = d.Version.GitVersion
+	}
+	// a run the store cannot take now is written once it finished, so
+	// the spool never holds a run stuck running
+	id, err := dao.Db_tryInsert(run)
+	common.LogErr(err)
+	run.Id = id
+	return run
 }
-type GainKubernetes interface {
-	GainPods() error
-	GainNodes() error
-	GainServices() error
-}
-
-func gainResource(a GainKubernetes) {
-	ThreadCountGet.Add(3)
-	go a.GainPods()
-	go a.GainNodes()
-	go a.GainServices()
 
+// finishRun records how the run ended. The results are found by the tag of
+// the run, their run id stays 0 when the run row had to be spooled.
+func finishRun(run *model.Runs, results []model.RunResults, end time.Time) {
+	run.End_time = common.RecordTime(end)
+	run.Status = model.RunCompleted
+	for i := range results {
+		if results[i].Status == model.CollectorFailed {
+			run.Status = model.RunPartial
+		}
+	}
+	if aborted() {
+		run.Status = model.RunInterrupted
+	}
+	var err error
+	if run.Id != 0 {
+		_, err = dao.Db_update(run, "End_time", "Status")
+	} else {
+		run.Id, err = dao.Db_insert(run)
+	}
+	if err != dao.ErrSpooled {
+		common.LogErr(err)
+	}
+	for i := range results {
+		results[i].Run_id = run.Id
+		if _, err := dao.Db_insert(&results[i]); err != dao.ErrSpooled {
+			common.LogErr(err)
+		}
+	}
 }
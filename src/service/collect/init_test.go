@@ -1,6 +1,332 @@
 package collect
 
-import "testing"
+import (
+	"context"
+	"dao"
+	"errors"
+	model "model/collect"
+	"net"
+	"service/collect/fakeapi"
+	"testing"
+	"time"
+)
+
+// fakeCluster points the collector at a fake apiserver serving two
+// namespaces, three pods, two nodes, a service and an event, and its writes
+// at a MemStore.
+func fakeCluster(t *testing.T) (*fakeapi.Server, *dao.MemStore) {
+	srv := fakeapi.New()
+	srv.Add("v1", "namespaces", "Namespace", fakeapi.Namespace("default"), fakeapi.Namespace("kube-system"))
+	srv.Add("v1", "pods", "Pod",
+		fakeapi.Pod("default", "web-1", "node-1"),
+		fakeapi.Pod("default", "web-2", "node-1"),
+		fakeapi.Pod("kube-system", "dns", "node-2"))
+	srv.Add("v1", "nodes", "Node", fakeapi.Node("node-1"), fakeapi.Node("node-2"))
+	srv.Add("v1", "services", "Service", fakeapi.Service("default", "web"))
+	srv.Add("v1", "events", "Event", fakeapi.Event("default", "web-1", "Pod", "BackOff"))
+	store := dao.NewMemStore()
+
+	master, defaultStore, pageSize, timeout := KuberMasterIp, dao.DefaultStore, ListPageSize, kubeClient.Timeout
+	dao.DefaultStore = store
+	ListPageSize = 2
+	kubeClient.Timeout = 200 * time.Millisecond
+	if err := Init(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		srv.Close()
+		KuberMasterIp, dao.DefaultStore, ListPageSize, kubeClient.Timeout = master, defaultStore, pageSize, timeout
+	})
+	return srv, store
+}
+
+func runResult(t *testing.T, store *dao.MemStore, kind string) model.RunResults {
+	for _, row := range store.Rows("run_results") {
+		if r := row.(*model.RunResults); r.Kind == kind {
+			return *r
+		}
+	}
+	t.Fatalf("no result for %s", kind)
+	return model.RunResults{}
+}
+
+func deletedInventory(store *dao.MemStore, table string) []string {
+	var names []string
+	for _, row := range store.Inventory(table) {
+		if row.Deleted_at != "" {
+			names = append(names, row.Name)
+		}
+	}
+	return names
+}
+
 func TestRunOneCycle(t *testing.T) {
+	srv, store := fakeCluster(t)
+	RunOneCycle()
+
+	runs := store.Rows("runs")
+	if len(runs) != 1 || runs[0].(*model.Runs).Status != model.RunCompleted {
+		t.Fatalf("runs = %+v", runs)
+	}
+	if v := runs[0].(*model.Runs).Server_version; v != "v1.5.2-fake" {
+		t.Errorf("server version = %q", v)
+	}
+	pods := store.Rows("pods")
+	if len(pods) != 3 {
+		t.Fatalf("%d pods stored", len(pods))
+	}
+	for _, row := range pods {
+		if p := row.(*model.Pods); p.All_pod_numbers != "3" || p.All_container_numbers != "3" {
+			t.Errorf("pod totals = %s %s", p.All_pod_numbers, p.All_container_numbers)
+		}
+	}
+	if srv.Requests("/api/v1/pods") != 2 {
+		t.Errorf("pods listed in %d pages", srv.Requests("/api/v1/pods"))
+	}
+	if n := len(store.Rows("nodes")); n != 2 {
+		t.Errorf("%d nodes stored", n)
+	}
+	services := store.Rows("services")
+	if len(services) != 1 || services[0].(*model.Services).Service_numbers != "1" {
+		t.Errorf("services = %+v", services)
+	}
+	if r := runResult(t, store, "pods"); r.Status != model.CollectorOk || r.Items != 3 || r.Encoding != "json" {
+		t.Errorf("pods result = %+v", r)
+	}
+	if r := runResult(t, store, "deployments"); r.Status != model.CollectorUnsupported {
+		t.Errorf("deployments result = %+v", r)
+	}
+
+	srv.Remove("v1", "pods", func(item interface{}) bool { return item.(*model.Pod).Name == "web-2" })
+	RunOneCycle()
+	if deleted := deletedInventory(store, "pod_inventory"); len(deleted) != 1 || deleted[0] != "web-2" {
+		t.Errorf("deleted pods = %v", deleted)
+	}
+}
+
+func TestRunOneCycleFailures(t *testing.T) {
+	for name, faults := range map[string][]fakeapi.Fault{
+		"timeout":        {fakeapi.Timeout},
+		"internal error": {fakeapi.InternalError},
+		"gone":           {fakeapi.Pass, fakeapi.Gone},
+		"malformed":      {fakeapi.Malformed},
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv, store := fakeCluster(t)
+			RunOneCycle()
+			srv.Remove("v1", "pods", func(item interface{}) bool { return item.(*model.Pod).Name == "web-2" })
+			srv.Add("v1", "pods", "Pod", fakeapi.Pod("default", "web-3", "node-2"))
+			srv.Fail("/api/v1/pods", faults...)
+			RunOneCycle()
+
+			runs := store.Rows("runs")
+			if len(runs) != 2 || runs[1].(*model.Runs).Status != model.RunPartial {
+				t.Fatalf("runs = %+v", runs)
+			}
+			var failed, ok int
+			for _, row := range store.Rows("run_results") {
+				r := row.(*model.RunResults)
+				if r.Tag != runs[1].(*model.Runs).Tag {
+					continue
+				}
+				switch {
+				case r.Kind == "pods" && r.Status == model.CollectorFailed && r.Error != "":
+					failed++
+				case r.Kind == "nodes" && r.Status == model.CollectorOk:
+					ok++
+				}
+			}
+			if failed != 1 || ok != 1 {
+				t.Errorf("pods failed %d, nodes ok %d", failed, ok)
+			}
+			// an incomplete list must not mark the pods it missed deleted
+			if deleted := deletedInventory(store, "pod_inventory"); len(deleted) != 0 {
+				t.Errorf("deleted pods = %v", deleted)
+			}
+		})
+	}
+}
+
+// staticShard owns the namespaces listed.
+type staticShard map[string]bool
+
+func (s staticShard) OwnsNamespace(namespace string) bool { return s[namespace] }
+func (s staticShard) OwnsClusterScoped() bool             { return s[""] }
+func (s staticShard) Describe() string                    { return "static" }
+
+func TestShardedCycle(t *testing.T) {
+	srv, store := fakeCluster(t)
+	RunOneCycle()
+	defer func() { CurrentShard = nil }()
+	CurrentShard = staticShard{"default": true}
+	srv.Remove("v1", "pods", func(item interface{}) bool { return item.(*model.Pod).Name == "web-2" })
+	RunOneCycle()
+
+	runs := store.Rows("runs")
+	run := runs[len(runs)-1].(*model.Runs)
+	if run.Status != model.RunCompleted || run.Shard != "static" {
+		t.Fatalf("run = %+v", run)
+	}
+	var names []string
+	for _, row := range store.Rows("pods") {
+		if p := row.(*model.Pods); p.Tag == run.Tag {
+			names = append(names, p.Namespace+"/"+p.Pod_name)
+		}
+	}
+	if len(names) != 1 || names[0] != "default/web-1" {
+		t.Errorf("sharded pods = %v", names)
+	}
+	if srv.Requests("/api/v1/namespaces/default/pods") != 1 || srv.Requests("/api/v1/namespaces/kube-system/pods") != 0 {
+		t.Error("the shard was not listed by namespace")
+	}
+	for _, row := range store.Rows("run_results") {
+		r := row.(*model.RunResults)
+		if r.Tag == run.Tag && r.Kind == "nodes" && r.Status != model.CollectorOtherShard {
+			t.Errorf("nodes result = %+v", r)
+		}
+	}
+	// only the shard is swept: dns in kube-system was not listed but lives on
+	if deleted := deletedInventory(store, "pod_inventory"); len(deleted) != 1 || deleted[0] != "web-2" {
+		t.Errorf("deleted pods = %v", deleted)
+	}
+
+	// kube-system is deleted: no shard owns it, the owner of the
+	// cluster-scoped kinds sweeps its pods
+	srv.Remove("v1", "namespaces", func(item interface{}) bool { return item.(*model.Namespace).Name == "kube-system" })
+	RunOneCycle()
+	if deleted := deletedInventory(store, "pod_inventory"); len(deleted) != 1 {
+		t.Errorf("deleted pods = %v, swept by a shard without the cluster-scoped kinds", deleted)
+	}
+	CurrentShard = staticShard{"default": true, "": true}
+	RunOneCycle()
+	if deleted := deletedInventory(store, "pod_inventory"); len(deleted) != 2 {
+		t.Errorf("deleted pods = %v, the pods of the deleted namespace live on", deleted)
+	}
+}
+func TestAbortCycle(t *testing.T) {
+	srv, store := fakeCluster(t)
+	kubeClient.Timeout = time.Minute
+	t.Cleanup(func() { cycleCtx, abortCycles = context.WithCancel(context.Background()) })
+	srv.Fail("/api/v1/pods", fakeapi.Timeout)
+
+	done := make(chan struct{})
+	go func() {
+		RunOneCycle()
+		close(done)
+	}()
+	for srv.Requests("/api/v1/pods") == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	Abort()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the cycle did not stop when aborted")
+	}
+	RunOneCycle()
+
+	runs := store.Rows("runs")
+	if len(runs) != 1 || runs[0].(*model.Runs).Status != model.RunInterrupted {
+		t.Fatalf("runs = %+v", runs)
+	}
+	if deleted := deletedInventory(store, "pod_inventory"); len(deleted) != 0 {
+		t.Errorf("deleted pods = %v", deleted)
+	}
+}
+func TestInterruptCycle(t *testing.T) {
+	srv, store := fakeCluster(t)
+	kubeClient.Timeout = time.Minute
+	srv.Fail("/api/v1/pods", fakeapi.Timeout)
+
+	done := make(chan struct{})
+	go func() {
+		RunOneCycle()
+		close(done)
+	}()
+	for srv.Requests("/api/v1/pods") == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	Interrupt()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the cycle did not stop when interrupted")
+	}
+	// unlike Abort, the next cycle runs
+	RunOneCycle()
+
+	runs := store.Rows("runs")
+	if len(runs) != 2 || runs[0].(*model.Runs).Status != model.RunInterrupted || runs[1].(*model.Runs).Status != model.RunCompleted {
+		t.Fatalf("runs = %+v", runs)
+	}
+}
+func TestPingAndStalled(t *testing.T) {
+	srv, _ := fakeCluster(t)
+	if _, err := Ping(); err != nil || !KuberMasterStatus {
+		t.Fatalf("ping: %v", err)
+	}
+	srv.Close()
+	if _, err := Ping(); err == nil || KuberMasterStatus {
+		t.Error("ping of a closed apiserver succeeded")
+	}
+
+	cycleStarted()
+	defer cycleFinished()
+	if err := Stalled(time.Minute); err != nil {
+		t.Error(err)
+	}
+	progressLock.Lock()
+	progressed = progressed.Add(-2 * time.Minute)
+	progressLock.Unlock()
+	if err := Stalled(time.Minute); err == nil {
+		t.Error("a cycle without progress for two minutes is not stalled")
+	}
+}
+func TestRunSpooledWhileStoreDown(t *testing.T) {
+	_, store := fakeCluster(t)
+	if err := dao.InitSpool(t.TempDir(), 64<<20); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		dao.DefaultSpool.Close()
+		dao.DefaultSpool = nil
+	})
+	store.Err = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	RunOneCycle()
+	if rows := store.Rows("runs"); len(rows) != 0 {
+		t.Fatalf("runs written while the store was down: %+v", rows)
+	}
+
+	store.Err = nil
+	if err := dao.DefaultSpool.Replay(); err != nil {
+		t.Fatal(err)
+	}
+	runs := store.Rows("runs")
+	if len(runs) != 1 {
+		t.Fatalf("runs = %+v", runs)
+	}
+	run := runs[0].(*model.Runs)
+	// the inventory is not spooled, so the run is partial but over
+	if run.Status == model.RunRunning || run.End_time == "" {
+		t.Errorf("replayed run = %+v", run)
+	}
+	var results int
+	for _, row := range store.Rows("run_results") {
+		if row.(*model.RunResults).Tag == run.Tag {
+			results++
+		}
+	}
+	if results == 0 {
+		t.Error("no results replayed for the run")
+	}
+}
+func TestRejectedRowsFailTheCollector(t *testing.T) {
+	_, store := fakeCluster(t)
+	store.Err = errors.New("Error 1406: Data too long for column 'pod_name'")
 	RunOneCycle()
-}
\ No newline at end of file
+	for _, r := range LastResults() {
+		if (r.Kind == "pods" || r.Kind == "nodes") && (r.Status != model.CollectorFailed || r.Error != store.Err.Error()) {
+			t.Errorf("%s = %+v", r.Kind, r)
+		}
+	}
+}
@@ -10,122 +10,207 @@ import (
 	"io/ioutil"
 	"encoding/json"
 	model "model/collect"
-	"log"
+	"strings"
+
+	"github.com/astaxie/beego/orm"
 )
 
-var PodList model.PodList
-var NodeList model.NodeList
-var ServiceList model.ServiceList_k
-var all_containers int
-var KuberMasterIp string
+var KuberMasterIp = "http://10.110.18.107:8080"
 var KuberMasterStatus bool
 
-func init() {
+// kubeClient gives up on an apiserver request after a minute, a hung
+// apiserver must not stall the cycle forever.
+var kubeClient = &http.Client{Timeout: time.Minute}
 
-	KuberMasterIp = "http://10.110.18.107:8080"
-	_, err := http.Get(KuberMasterIp + "/version")
-	if err != nil {
-		common.LogErr(err)
-		KuberMasterStatus = false
-	} else {
-		KuberMasterStatus = true
+// objectTime formats a time taken from an object like the record times, an
+// unset time gives an empty string.
+func objectTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
-	log.Printf("%s\t%s\t%s\t", "KuberMasterStatus status is ", strconv.FormatBool(KuberMasterStatus), time.Now())
-
-}
-func get_time() string {
-	get_time := time.Now().UTC().Add(8 * time.Hour).Format("2006-01-02 15:04:05")
-	return get_time
+	return common.RecordTime(t)
 }
+
 func GainResourceFromK8s(resource interface{}, urls string) error {
-	resp, err := http.Get(KuberMasterIp + urls)
-	common.LogErr(err)
+	resp, err := kubeSource.get(urls, nil)
+	if err != nil {
+		common.LogErr(err)
+		return err
+	}
+	progress()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("get %s: %s", urls, resp.Status)
+		common.LogErr(err)
+		return err
+	}
 	body, err := ioutil.ReadAll(resp.Body)
-	common.LogErr(err)
-	err = json.Unmarshal(body, &resource)
-	common.LogErr(err)
-	return nil
+	if err != nil {
+		common.LogErr(err)
+		return err
+	}
+	return decodeTolerant(body, resource, urls)
 }
 
-func (resource KubernetesAllResource) GainPods() error {
-	GainResourceFromK8s(&PodList, "/api/v1/pods")
-	i := 0
-	n_containers := 0
-	tagTemp := common.Gen_id(5)
-	for _, v := range PodList.Items {
-		var x = resource.pods
-		i = i + 1
-		x.All_pod_numbers = strconv.Itoa(len(PodList.Items))
-//		x.Pod_No = strconv.Itoa(i)
-		x.Pod_hostIP = v.Status.HostIP
-		x.Pod_name = v.Name
-		x.Create_time = v.CreationTimestamp.Format("2006-01-02 15:04:05")
-		x.Record_time = get_time()
-		x.Containers_numbers = strconv.Itoa(len(v.Status.Conditions))
-		tem_containers := len(v.Status.Conditions)
-		n_containers = tem_containers + n_containers
-		if i == len(PodList.Items) {
-			all_containers = n_containers
-		}
-		x.All_container_numbers = strconv.Itoa(all_containers)
-		x.Tag = tagTemp
-		dao.Db_insert(&x)
+// decodeTolerant decodes body into resource. Unknown fields are ignored by
+// encoding/json; a field whose type changed between releases is skipped and
+// logged instead of failing the whole list.
+func decodeTolerant(body []byte, resource interface{}, urls string) error {
+	err := json.Unmarshal(body, resource)
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+		common.DebugPrint("get", urls, "skipped a field of an unexpected type:", typeErr)
+		return nil
+	}
+	common.LogErr(err)
+	return err
+}
 
+func storePod(item interface{}, l *Listing) error {
+	v := *item.(*model.Pod)
+	var x model.Pods
+	x.Namespace = v.Namespace
+	x.Pod_name = v.Name
+	x.Uid = string(v.UID)
+	x.Pod_hostIP = v.Status.HostIP
+	x.Pod_IP = v.Status.PodIP
+	x.Node_name = v.Spec.NodeName
+	x.Phase = string(v.Status.Phase)
+	x.Reason = v.Status.Reason
+	x.Message = v.Status.Message
+	x.Restart_policy = string(v.Spec.RestartPolicy)
+	x.Qos_class = qosClass(v.Spec)
+	if ref := controllerRef(v.ObjectMeta); ref != nil {
+		x.Owner_kind = ref.Kind
+		x.Owner_name = ref.Name
+		x.Owner_uid = string(ref.UID)
 	}
-	common.DebugPrint("pods is insert")
-	ThreadCountGet.Done()
+	x.Create_time = v.CreationTimestamp.Format("2006-01-02 15:04:05")
+	if v.Status.StartTime != nil {
+		x.Start_time = objectTime(v.Status.StartTime.Time)
+	}
+	x.Record_time = l.recordTime()
+	x.Containers_numbers = strconv.Itoa(len(v.Spec.Containers))
+	x.Restart_count = strconv.Itoa(restartCount(v.Status.ContainerStatuses))
+	x.Tag = l.Run.Tag
+	if err := insertRow(&x); err != nil {
+		return err
+	}
+	if err := insertPodVolumeClaims(v, l); err != nil {
+		return err
+	}
+	if err := insertLabels("pods", v.ObjectMeta, l); err != nil {
+		return err
+	}
+	l.Touch(v.ObjectMeta)
+	l.Add("containers", len(v.Spec.Containers))
 	return nil
 }
 
-func (resource KubernetesAllResource) GainNodes() error {
-	GainResourceFromK8s(&NodeList, "/api/v1/nodes")
-
-	tagTemp := common.Gen_id(5)
-	for _, v := range NodeList.Items {
-		var nodes = resource.nodes
-		nodes.Node_name = v.Name
-		nodes.Create_time = v.CreationTimestamp.Format("2006-01-02 15:04:05")
-		fmt.Println(len(v.Status.Images))
-		for k, v := range v.Status.Capacity {
-			switch k {
-			case "cpu":
-				nodes.Numbers_cpu_core = v.String()
-			case "memory":
-				nodes.Memory_size = v.String()
-			case "alpha.kubernetes.io/nvidia-gpu":
-				nodes.Numbers_gpu_core = v.String()
-			case "pods":
-				nodes.Pod_limit = v.String()
-			}
-		}
-		nodes.Record_time = get_time()
-		nodes.Tag = tagTemp
-		dao.Db_insert(&nodes)
-
+// insertRow writes row; a row the spool took counts as written.
+func insertRow(row interface{}) error {
+	if _, err := dao.Db_insert(row); err != nil && err != dao.ErrSpooled {
+		return err
 	}
-	common.DebugPrint("nodes is insert")
-	ThreadCountGet.Done()
 	return nil
+}
 
+// finishPods fills in the totals of the run, known once every pod is stored.
+func finishPods(l *Listing) error {
+	_, err := dao.Db_updateByTag("pods", l.Run.Tag, orm.Params{
+		"All_pod_numbers":       strconv.Itoa(l.Items),
+		"All_container_numbers": strconv.Itoa(l.Counter("containers")),
+	})
+	return err
+}
+
+func restartCount(statuses []model.ContainerStatus) int {
+	n := 0
+	for _, s := range statuses {
+		n = n + int(s.RestartCount)
+	}
+	return n
 }
 
-func (resource KubernetesAllResource) GainServices() error {
-	GainResourceFromK8s(&ServiceList, "/api/v1/services")
-	tagTemp := common.Gen_id(5)
-	for _, v := range ServiceList.Items {
-		var service = resource.services
-		service.Create_time = v.CreationTimestamp.Format("2006-01-02 15:04:05")
-		service.Service_name = v.Name
-		service.Service_numbers = strconv.Itoa(len(ServiceList.Items))
-		service.Record_time = get_time()
-		for k,v:=range v.Labels {
-			common.DebugPrint(k,v)
+func storeNode(item interface{}, l *Listing) error {
+	v := *item.(*model.Node)
+	var nodes model.Nodes
+	nodes.Node_name = v.Name
+	nodes.Create_time = v.CreationTimestamp.Format("2006-01-02 15:04:05")
+	for k, v := range v.Status.Capacity {
+		switch k {
+		case "cpu":
+			nodes.Numbers_cpu_core = v.String()
+		case "memory":
+			nodes.Memory_size = v.String()
+		case model.ResourceNvidiaGPU, model.ResourceNvidiaGPUDevice:
+			nodes.Numbers_gpu_core = v.String()
+		case "pods":
+			nodes.Pod_limit = v.String()
 		}
+	}
+	nodes.Allocatable_cpu = quantityString(v.Status.Allocatable, model.ResourceCPU)
+	nodes.Allocatable_gpu = quantityString(v.Status.Allocatable, model.ResourceNvidiaGPU)
+	if nodes.Allocatable_gpu == "" {
+		nodes.Allocatable_gpu = quantityString(v.Status.Allocatable, model.ResourceNvidiaGPUDevice)
+	}
+	nodes.Allocatable_memory = quantityString(v.Status.Allocatable, model.ResourceMemory)
+	nodes.Allocatable_pods = quantityString(v.Status.Allocatable, model.ResourcePods)
+	nodes.Unschedulable = v.Spec.Unschedulable
+	nodes.Ready = nodeReady(v.Status.Conditions)
+	info := v.Status.NodeInfo
+	nodes.Kernel_version = info.KernelVersion
+	nodes.Os_image = info.OSImage
+	nodes.Operating_system = info.OperatingSystem
+	nodes.Architecture = info.Architecture
+	nodes.Runtime_version = info.ContainerRuntimeVersion
+	nodes.Kubelet_version = info.KubeletVersion
+	nodes.Kube_proxy_version = info.KubeProxyVersion
+	nodes.Addresses = nodeAddresses(v.Status.Addresses)
+	nodes.Taints = nodeTaints(v)
+	nodes.Record_time = l.recordTime()
+	nodes.Tag = l.Run.Tag
+	if err := insertRow(&nodes); err != nil {
+		return err
+	}
+	if err := insertNodeConditions(v, l); err != nil {
+		return err
+	}
+	if err := insertLabels("nodes", v.ObjectMeta, l); err != nil {
+		return err
+	}
+	l.Touch(v.ObjectMeta)
+	return nil
+}
 
-		service.Tag = tagTemp
-		dao.Db_insert(&service)
+func storeService(item interface{}, l *Listing) error {
+	v := *item.(*model.Service)
+	var service model.Services
+	service.Create_time = v.CreationTimestamp.Format("2006-01-02 15:04:05")
+	service.Namespace = v.Namespace
+	service.Service_name = v.Name
+	service.Uid = string(v.UID)
+	service.Type = string(v.Spec.Type)
+	service.Cluster_ip = v.Spec.ClusterIP
+	service.Ports = servicePorts(v.Spec.Ports)
+	service.Selector = formatLabels(v.Spec.Selector)
+	service.External_ips = strings.Join(v.Spec.ExternalIPs, ",")
+	service.Lb_ingress = loadBalancerIngress(v.Status.LoadBalancer)
+	service.External_name = v.Spec.ExternalName
+	service.Affinity = string(v.Spec.SessionAffinity)
+	service.Record_time = l.recordTime()
+	service.Tag = l.Run.Tag
+	if err := insertRow(&service); err != nil {
+		return err
+	}
+	if err := insertLabels("services", v.ObjectMeta, l); err != nil {
+		return err
 	}
-	common.DebugPrint("service is insert")
-	ThreadCountGet.Done()
+	l.Touch(v.ObjectMeta)
 	return nil
 }
+
+// finishServices fills in the service count of the run.
+func finishServices(l *Listing) error {
+	_, err := dao.Db_updateByTag("services", l.Run.Tag, orm.Params{"Service_numbers": strconv.Itoa(l.Items)})
+	return err
+}
@@ -0,0 +1,54 @@
+package collect
+
+import (
+	"common"
+	"dao"
+	model "model/collect"
+)
+
+// inventory keeps the current-state table of one kind in step with a run.
+// Objects are touched as they are collected; sweep then marks everything the
+// run did not see as deleted, but only if every object could be recorded.
+type inventory struct {
+	table      string
+	run        *model.Runs
+	namespaces []string
+	known      []string
+	err        error
+}
+
+// newInventory keeps the inventory of kind; only objects of namespaces are
+// swept, unless it is nil. When known is set the objects of the namespaces
+// not in it, deleted ones no shard lists any more, are swept as well.
+func newInventory(kind string, run *model.Runs, namespaces []string, known []string) *inventory {
+	return &inventory{table: model.InventoryTables[kind], run: run, namespaces: namespaces, known: known}
+}
+
+func (inv *inventory) touch(meta model.ObjectMeta) {
+	err := dao.Db_upsertInventory(inv.table, string(meta.UID), meta.Namespace, meta.Name, inv.run.Start_time, inv.run.Tag)
+	if err != nil {
+		inv.err = err
+	}
+}
+
+func (inv *inventory) sweep() error {
+	if inv.err != nil {
+		common.DebugPrint(inv.table, "is not swept, an upsert failed:", inv.err)
+		return inv.err
+	}
+	n, err := dao.Db_markInventoryDeleted(inv.table, inv.run.Tag, inv.run.Start_time, inv.namespaces)
+	if err != nil {
+		return err
+	}
+	if inv.known != nil {
+		orphans, err := dao.Db_markOrphanedInventoryDeleted(inv.table, inv.run.Start_time, inv.known)
+		if err != nil {
+			return err
+		}
+		n += orphans
+	}
+	if n > 0 {
+		common.DebugPrint(inv.table, n, "objects are marked deleted")
+	}
+	return nil
+}
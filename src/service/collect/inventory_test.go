@@ -0,0 +1,81 @@
+package collect
+
+import (
+	"common"
+	"dao"
+	"errors"
+	model "model/collect"
+	"sort"
+	"testing"
+)
+
+// memListing points the store at a MemStore for the test and returns a
+// listing of kind in a run started now.
+func memListing(t *testing.T, kind string) (*dao.MemStore, *Listing) {
+	store := dao.NewMemStore()
+	saved := dao.DefaultStore
+	dao.DefaultStore = store
+	t.Cleanup(func() { dao.DefaultStore = saved })
+	return store, &Listing{Run: &model.Runs{Tag: "run-1", Start_time: common.RecordNow()}, Kind: kind}
+}
+
+func TestInventorySweep(t *testing.T) {
+	store, l := memListing(t, "pods")
+	web1 := model.ObjectMeta{Namespace: "default", Name: "web-1", UID: "uid-1"}
+	web2 := model.ObjectMeta{Namespace: "default", Name: "web-2", UID: "uid-2"}
+	dns := model.ObjectMeta{Namespace: "kube-system", Name: "dns", UID: "uid-3"}
+
+	inv := newInventory("pods", l.Run, nil, nil)
+	for _, meta := range []model.ObjectMeta{web1, web2, dns} {
+		inv.touch(meta)
+	}
+	if err := inv.sweep(); err != nil {
+		t.Fatal(err)
+	}
+	if rows := store.Inventory("pod_inventory"); len(rows) != 3 {
+		t.Fatalf("inventory = %+v", rows)
+	}
+	if deleted := deletedInventory(store, "pod_inventory"); len(deleted) != 0 {
+		t.Fatalf("deleted %v", deleted)
+	}
+	next := &model.Runs{Tag: "run-2", Start_time: l.Run.Start_time}
+
+	// a run that could not record every object must not sweep the rest
+	store.Err = errors.New("database is gone")
+	inv = newInventory("pods", next, nil, nil)
+	inv.touch(web1)
+	store.Err = nil
+	if err := inv.sweep(); err == nil {
+		t.Error("swept after a failed upsert")
+	}
+	if deleted := deletedInventory(store, "pod_inventory"); len(deleted) != 0 {
+		t.Fatalf("deleted %v after a failed upsert", deleted)
+	}
+
+	// a shard only sweeps its own namespaces
+	inv = newInventory("pods", next, []string{"default"}, nil)
+	inv.touch(web1)
+	if err := inv.sweep(); err != nil {
+		t.Fatal(err)
+	}
+	if deleted := deletedInventory(store, "pod_inventory"); len(deleted) != 1 || deleted[0] != "web-2" {
+		t.Fatalf("deleted %v", deleted)
+	}
+
+	inv = newInventory("pods", next, nil, nil)
+	inv.touch(web1)
+	inv.touch(web2)
+	if err := inv.sweep(); err != nil {
+		t.Fatal(err)
+	}
+	deleted := deletedInventory(store, "pod_inventory")
+	sort.Strings(deleted)
+	if len(deleted) != 1 || deleted[0] != "dns" {
+		t.Errorf("deleted %v, web-2 should be back", deleted)
+	}
+	for _, row := range store.Inventory("pod_inventory") {
+		if row.Name == "web-1" && (row.Last_tag != "run-2" || row.First_seen != l.Run.Start_time) {
+			t.Errorf("web-1 = %+v", row)
+		}
+	}
+}
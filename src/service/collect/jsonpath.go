@@ -0,0 +1,248 @@
+package collect
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a compiled subset of the kubectl JSONPath syntax:
+// {.spec.replicas}, {.items[0].name}, {.spec.ports[*].port},
+// {.metadata.labels['app.kubernetes.io/name']} and filters such as
+// {.status.conditions[?(@.type=="Ready")].status}.
+type jsonPath struct {
+	expr  string
+	steps []pathStep
+}
+
+type pathStep struct {
+	field  string
+	index  int
+	all    bool
+	filter *pathFilter
+}
+
+type pathFilter struct {
+	path  []pathStep
+	equal bool
+	value string
+}
+
+const noIndex = -1 << 31
+
+func compileJSONPath(expr string) (*jsonPath, error) {
+	s := strings.TrimSpace(expr)
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = s[1 : len(s)-1]
+	}
+	s = strings.TrimPrefix(s, "$")
+	steps, err := parseSteps(s)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpath %q: %v", expr, err)
+	}
+	return &jsonPath{expr: expr, steps: steps}, nil
+}
+
+func parseSteps(s string) ([]pathStep, error) {
+	var steps []pathStep
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			end := strings.IndexAny(s[1:], ".[")
+			if end < 0 {
+				end = len(s) - 1
+			}
+			name := s[1 : end+1]
+			if name == "" {
+				return nil, fmt.Errorf("empty field name")
+			}
+			steps = append(steps, pathStep{field: name, index: noIndex})
+			s = s[end+1:]
+		case '[':
+			end := closingBracket(s)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [")
+			}
+			step, err := parseBracket(s[1:end])
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", s)
+		}
+	}
+	return steps, nil
+}
+
+// closingBracket finds the ] matching the [ s starts with, skipping quoted
+// strings.
+func closingBracket(s string) int {
+	var quote byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseBracket(s string) (pathStep, error) {
+	step := pathStep{index: noIndex}
+	switch {
+	case s == "*":
+		step.all = true
+	case strings.HasPrefix(s, "?(") && strings.HasSuffix(s, ")"):
+		f, err := parseFilter(s[2 : len(s)-1])
+		if err != nil {
+			return step, err
+		}
+		step.filter = f
+	case len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]:
+		step.field = s[1 : len(s)-1]
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return step, fmt.Errorf("bad subscript [%s]", s)
+		}
+		step.index = n
+	}
+	return step, nil
+}
+
+func parseFilter(s string) (*pathFilter, error) {
+	f := &pathFilter{}
+	op := "=="
+	i := strings.Index(s, op)
+	if j := strings.Index(s, "!="); j >= 0 && (i < 0 || j < i) {
+		op, i = "!=", j
+	}
+	if i < 0 || !strings.HasPrefix(s, "@") {
+		return nil, fmt.Errorf("unsupported filter %q", s)
+	}
+	f.equal = op == "=="
+	path, err := parseSteps(strings.TrimSpace(s[1:i]))
+	if err != nil {
+		return nil, err
+	}
+	f.path = path
+	value := strings.TrimSpace(s[i+len(op):])
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	f.value = value
+	return f, nil
+}
+
+// Find evaluates the path against a decoded JSON document and returns every
+// value it selects.
+func (p *jsonPath) Find(doc interface{}) []interface{} {
+	return walk([]interface{}{doc}, p.steps)
+}
+
+// String evaluates the path and formats the matches the way kubectl prints
+// them, separated by commas.
+func (p *jsonPath) String(doc interface{}) string {
+	matches := p.Find(doc)
+	s := make([]string, len(matches))
+	for i, m := range matches {
+		s[i] = jsonString(m)
+	}
+	return strings.Join(s, ",")
+}
+
+func walk(nodes []interface{}, steps []pathStep) []interface{} {
+	for _, step := range steps {
+		var next []interface{}
+		for _, node := range nodes {
+			next = append(next, step.apply(node)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func (step pathStep) apply(node interface{}) []interface{} {
+	switch {
+	case step.field != "":
+		if m, ok := node.(map[string]interface{}); ok {
+			if v, ok := m[step.field]; ok {
+				return []interface{}{v}
+			}
+		}
+	case step.all:
+		switch v := node.(type) {
+		case []interface{}:
+			return v
+		case map[string]interface{}:
+			all := make([]interface{}, 0, len(v))
+			for _, item := range v {
+				all = append(all, item)
+			}
+			return all
+		}
+	case step.filter != nil:
+		list, _ := node.([]interface{})
+		var matched []interface{}
+		for _, item := range list {
+			if step.filter.matches(item) {
+				matched = append(matched, item)
+			}
+		}
+		return matched
+	case step.index != noIndex:
+		if list, ok := node.([]interface{}); ok {
+			i := step.index
+			if i < 0 {
+				i = len(list) + i
+			}
+			if i >= 0 && i < len(list) {
+				return []interface{}{list[i]}
+			}
+		}
+	}
+	return nil
+}
+
+func (f *pathFilter) matches(item interface{}) bool {
+	values := walk([]interface{}{item}, f.path)
+	found := false
+	for _, v := range values {
+		if jsonString(v) == f.value {
+			found = true
+		}
+	}
+	if f.equal {
+		return found
+	}
+	return len(values) > 0 && !found
+}
+
+func jsonString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case nil:
+		return ""
+	case float64, bool:
+		return fmt.Sprint(x)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,48 @@
+package collect
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(`{
+		"metadata": {"name": "web", "labels": {"app.kubernetes.io/name": "shop"}},
+		"spec": {"replicas": 3, "paused": false, "ports": [{"port": 80}, {"port": 443}]},
+		"status": {"conditions": [
+			{"type": "Ready", "status": "True"},
+			{"type": "Synced", "status": "False"}
+		]}
+	}`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"{.metadata.name}":                                "web",
+		".spec.replicas":                                  "3",
+		"{.spec.paused}":                                  "false",
+		"{.spec.ports[*].port}":                           "80,443",
+		"{.spec.ports[-1].port}":                          "443",
+		"{.metadata.labels['app.kubernetes.io/name']}":    "shop",
+		`{.status.conditions[?(@.type=="Ready")].status}`: "True",
+		`{.status.conditions[?(@.status!="True")].type}`:  "Synced",
+		"{.spec.missing}":                                 "",
+		"{.spec.ports[0]}":                                `{"port":80}`,
+	}
+	for expr, want := range cases {
+		p, err := compileJSONPath(expr)
+		if err != nil {
+			t.Errorf("compile %s: %v", expr, err)
+			continue
+		}
+		if got := p.String(doc); got != want {
+			t.Errorf("%s = %q, want %q", expr, got, want)
+		}
+	}
+	for _, bad := range []string{"{.spec..x}", "{.spec[}", "{.spec[x]}", "{spec}"} {
+		if _, err := compileJSONPath(bad); err == nil {
+			t.Errorf("compile %s should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package collect
+
+import (
+	model "model/collect"
+	"strings"
+)
+
+// AnnotationAllowList names the annotations stored next to the labels. An
+// entry ending in * matches every key with that prefix.
+var AnnotationAllowList []string
+
+func annotationAllowed(key string) bool {
+	for _, allowed := range AnnotationAllowList {
+		if strings.HasSuffix(allowed, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+		} else if key == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// insertLabels stores the labels and allow-listed annotations of an object.
+func insertLabels(kind string, meta model.ObjectMeta, l *Listing) error {
+	for k, v := range meta.Labels {
+		if err := insertLabel(kind, meta, k, v, false, l); err != nil {
+			return err
+		}
+	}
+	for k, v := range meta.Annotations {
+		if annotationAllowed(k) {
+			if err := insertLabel(kind, meta, k, v, true, l); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func insertLabel(kind string, meta model.ObjectMeta, key string, value string, annotation bool, l *Listing) error {
+	var label model.ObjectLabels
+	label.Kind = kind
+	label.Namespace = meta.Namespace
+	label.Name = meta.Name
+	label.Uid = string(meta.UID)
+	label.Key = key
+	label.Value = value
+	label.Annotation = annotation
+	label.Record_time = l.recordTime()
+	label.Tag = l.Run.Tag
+	return insertRow(&label)
+}
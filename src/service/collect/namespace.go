@@ -0,0 +1,111 @@
+package collect
+
+import (
+	model "model/collect"
+	"sort"
+	"strings"
+)
+
+func storeNamespace(item interface{}, l *Listing) error {
+	v := *item.(*model.Namespace)
+	var namespace model.Namespaces
+	namespace.Namespace_name = v.Name
+	namespace.Phase = string(v.Status.Phase)
+	namespace.Create_time = v.CreationTimestamp.Format("2006-01-02 15:04:05")
+	namespace.Record_time = l.recordTime()
+	namespace.Tag = l.Run.Tag
+	if err := insertRow(&namespace); err != nil {
+		return err
+	}
+	if err := insertLabels("namespaces", v.ObjectMeta, l); err != nil {
+		return err
+	}
+	return nil
+}
+
+func storeResourceQuota(item interface{}, l *Listing) error {
+	v := *item.(*model.ResourceQuota)
+	if err := insertLabels("resourcequotas", v.ObjectMeta, l); err != nil {
+		return err
+	}
+	scopes := make([]string, len(v.Spec.Scopes))
+	for i, scope := range v.Spec.Scopes {
+		scopes[i] = string(scope)
+	}
+	// Status.Hard is what the quota controller enforces, Spec.Hard may
+	// not have been observed yet
+	hard := v.Status.Hard
+	if len(hard) == 0 {
+		hard = v.Spec.Hard
+	}
+	for _, name := range resourceNames(hard) {
+		var quota model.Quotas
+		quota.Namespace = v.Namespace
+		quota.Quota_name = v.Name
+		quota.Resource = string(name)
+		h := hard[name]
+		quota.Hard = h.String()
+		quota.Hard_milli = h.MilliValue()
+		if u, ok := v.Status.Used[name]; ok {
+			quota.Used = u.String()
+			quota.Used_milli = u.MilliValue()
+		}
+		quota.Scopes = strings.Join(scopes, ",")
+		quota.Record_time = l.recordTime()
+		quota.Tag = l.Run.Tag
+		if err := insertRow(&quota); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func storeLimitRange(item interface{}, l *Listing) error {
+	v := *item.(*model.LimitRange)
+	if err := insertLabels("limitranges", v.ObjectMeta, l); err != nil {
+		return err
+	}
+	for _, item := range v.Spec.Limits {
+		names := make(map[model.ResourceName]bool)
+		for _, list := range []model.ResourceList{item.Min, item.Max, item.Default, item.DefaultRequest, item.MaxLimitRequestRatio} {
+			for name := range list {
+				names[name] = true
+			}
+		}
+		for name := range names {
+			var limit model.LimitRanges
+			limit.Namespace = v.Namespace
+			limit.Limit_name = v.Name
+			limit.Type = string(item.Type)
+			limit.Resource = string(name)
+			limit.Min = quantityString(item.Min, name)
+			limit.Max = quantityString(item.Max, name)
+			limit.Default = quantityString(item.Default, name)
+			limit.Default_request = quantityString(item.DefaultRequest, name)
+			limit.Max_ratio = quantityString(item.MaxLimitRequestRatio, name)
+			limit.Record_time = l.recordTime()
+			limit.Tag = l.Run.Tag
+			if err := insertRow(&limit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resourceNames(list model.ResourceList) []model.ResourceName {
+	names := make([]model.ResourceName, 0, len(list))
+	for name := range list {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+func quantityString(list model.ResourceList, name model.ResourceName) string {
+	q, ok := list[name]
+	if !ok {
+		return ""
+	}
+	return q.String()
+}
@@ -0,0 +1,113 @@
+package collect
+
+import (
+	model "model/collect"
+	"testing"
+
+	"k8s.io/client-go/pkg/api/resource"
+)
+
+func TestStoreNamespace(t *testing.T) {
+	store, l := memListing(t, "namespaces")
+	ns := model.Namespace{
+		ObjectMeta: model.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}},
+		Status:     model.NamespaceStatus{Phase: model.NamespaceActive},
+	}
+	if err := storeNamespace(&ns, l); err != nil {
+		t.Fatal(err)
+	}
+	rows := store.Rows("namespaces")
+	if len(rows) != 1 {
+		t.Fatalf("%d namespaces stored", len(rows))
+	}
+	if n := rows[0].(*model.Namespaces); n.Namespace_name != "team-a" || n.Phase != "Active" || n.Tag != "run-1" {
+		t.Errorf("namespace = %+v", n)
+	}
+	if labels := store.Rows("object_labels"); len(labels) != 1 || labels[0].(*model.ObjectLabels).Kind != "namespaces" {
+		t.Errorf("labels = %+v", labels)
+	}
+}
+
+func TestStoreResourceQuota(t *testing.T) {
+	store, l := memListing(t, "resourcequotas")
+	quota := model.ResourceQuota{
+		ObjectMeta: model.ObjectMeta{Namespace: "team-a", Name: "compute"},
+		Spec: model.ResourceQuotaSpec{
+			Hard:   model.ResourceList{model.ResourceRequestsCPU: resource.MustParse("4")},
+			Scopes: []model.ResourceQuotaScope{model.ResourceQuotaScopeBestEffort},
+		},
+	}
+	// the controller has not observed the quota yet, Spec.Hard is all there is
+	if err := storeResourceQuota(&quota, l); err != nil {
+		t.Fatal(err)
+	}
+	rows := store.Rows("quotas")
+	if len(rows) != 1 {
+		t.Fatalf("%d quotas stored", len(rows))
+	}
+	if q := rows[0].(*model.Quotas); q.Resource != "requests.cpu" || q.Hard != "4" || q.Hard_milli != 4000 ||
+		q.Used != "" || q.Scopes != "BestEffort" {
+		t.Errorf("quota = %+v", q)
+	}
+
+	quota.Status = model.ResourceQuotaStatus{
+		Hard: model.ResourceList{
+			model.ResourceRequestsCPU:  resource.MustParse("2"),
+			model.ResourceLimitsMemory: resource.MustParse("1Gi"),
+		},
+		Used: model.ResourceList{model.ResourceRequestsCPU: resource.MustParse("500m")},
+	}
+	if err := storeResourceQuota(&quota, l); err != nil {
+		t.Fatal(err)
+	}
+	rows = store.Rows("quotas")[1:]
+	if len(rows) != 2 {
+		t.Fatalf("%d quotas stored", len(rows))
+	}
+	// sorted by resource name, the hard limits enforced by the controller
+	if q := rows[0].(*model.Quotas); q.Resource != "limits.memory" || q.Hard != "1Gi" || q.Used != "" {
+		t.Errorf("quota = %+v", q)
+	}
+	if q := rows[1].(*model.Quotas); q.Resource != "requests.cpu" || q.Hard_milli != 2000 ||
+		q.Used != "500m" || q.Used_milli != 500 {
+		t.Errorf("quota = %+v", q)
+	}
+}
+
+func TestStoreLimitRange(t *testing.T) {
+	store, l := memListing(t, "limitranges")
+	limits := model.LimitRange{
+		ObjectMeta: model.ObjectMeta{Namespace: "team-a", Name: "defaults"},
+		Spec: model.LimitRangeSpec{Limits: []model.LimitRangeItem{{
+			Type:           model.LimitTypeContainer,
+			Max:            model.ResourceList{model.ResourceCPU: resource.MustParse("2")},
+			Default:        model.ResourceList{model.ResourceCPU: resource.MustParse("500m")},
+			DefaultRequest: model.ResourceList{model.ResourceMemory: resource.MustParse("64Mi")},
+		}}},
+	}
+	if err := storeLimitRange(&limits, l); err != nil {
+		t.Fatal(err)
+	}
+	rows := store.Rows("limit_ranges")
+	if len(rows) != 2 {
+		t.Fatalf("%d limit ranges stored", len(rows))
+	}
+	for _, row := range rows {
+		r := row.(*model.LimitRanges)
+		if r.Namespace != "team-a" || r.Limit_name != "defaults" || r.Type != "Container" {
+			t.Errorf("limit range = %+v", r)
+		}
+		switch r.Resource {
+		case "cpu":
+			if r.Max != "2" || r.Default != "500m" || r.Min != "" || r.Default_request != "" {
+				t.Errorf("cpu = %+v", r)
+			}
+		case "memory":
+			if r.Default_request != "64Mi" || r.Max != "" {
+				t.Errorf("memory = %+v", r)
+			}
+		default:
+			t.Errorf("unexpected resource %s", r.Resource)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package collect
+
+import (
+	"common"
+	"encoding/json"
+	model "model/collect"
+	"strings"
+)
+
+// nodeReady returns the status of the Ready condition, Unknown when the node
+// does not report one.
+func nodeReady(conditions []model.NodeCondition) string {
+	for _, c := range conditions {
+		if c.Type == model.NodeReady {
+			return string(c.Status)
+		}
+	}
+	return string(model.ConditionUnknown)
+}
+
+func nodeAddresses(addresses []model.NodeAddress) string {
+	s := make([]string, len(addresses))
+	for i, a := range addresses {
+		s[i] = string(a.Type) + "=" + a.Address
+	}
+	return strings.Join(s, ",")
+}
+
+// nodeTaints formats the taints like kubectl does, key=value:Effect.
+func nodeTaints(node model.Node) string {
+	taints := node.Spec.Taints
+	if len(taints) == 0 {
+		if raw, ok := node.Annotations[model.TaintsAnnotationKey]; ok {
+			if err := json.Unmarshal([]byte(raw), &taints); err != nil {
+				common.DebugPrint("bad taints annotation on", node.Name, err)
+			}
+		}
+	}
+	s := make([]string, len(taints))
+	for i, t := range taints {
+		s[i] = t.Key
+		if t.Value != "" {
+			s[i] += "=" + t.Value
+		}
+		s[i] += ":" + string(t.Effect)
+	}
+	return strings.Join(s, ",")
+}
+
+func insertNodeConditions(node model.Node, l *Listing) error {
+	for _, c := range node.Status.Conditions {
+		var condition model.NodeConditions
+		condition.Node_name = node.Name
+		condition.Type = string(c.Type)
+		condition.Status = string(c.Status)
+		condition.Reason = c.Reason
+		condition.Message = c.Message
+		condition.Last_heartbeat_time = objectTime(c.LastHeartbeatTime.Time)
+		condition.Last_transition_time = objectTime(c.LastTransitionTime.Time)
+		condition.Record_time = l.recordTime()
+		condition.Tag = l.Run.Tag
+		if err := insertRow(&condition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package collect
+
+import (
+	"common"
+	model "model/collect"
+	"service/collect/fakeapi"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/pkg/api/resource"
+	"k8s.io/client-go/pkg/api/unversioned"
+)
+
+func TestNodeReady(t *testing.T) {
+	conditions := []model.NodeCondition{
+		{Type: model.NodeMemoryPressure, Status: model.ConditionTrue},
+		{Type: model.NodeReady, Status: model.ConditionFalse},
+	}
+	if ready := nodeReady(conditions); ready != "False" {
+		t.Errorf("ready = %s", ready)
+	}
+	if ready := nodeReady(conditions[:1]); ready != "Unknown" {
+		t.Errorf("ready without a Ready condition = %s", ready)
+	}
+}
+
+func TestNodeTaints(t *testing.T) {
+	var node model.Node
+	node.Annotations = map[string]string{
+		model.TaintsAnnotationKey: `[{"key":"dedicated","value":"gpu","effect":"NoSchedule"}]`,
+	}
+	if taints := nodeTaints(node); taints != "dedicated=gpu:NoSchedule" {
+		t.Errorf("taints from the annotation = %q", taints)
+	}
+	// the field of newer clusters wins over the annotation
+	node.Spec.Taints = []model.Taint{
+		{Key: "dedicated", Value: "db", Effect: model.TaintEffectNoSchedule},
+		{Key: "spot", Effect: model.TaintEffectPreferNoSchedule},
+	}
+	if taints := nodeTaints(node); taints != "dedicated=db:NoSchedule,spot:PreferNoSchedule" {
+		t.Errorf("taints = %q", taints)
+	}
+	node.Spec.Taints = nil
+	node.Annotations[model.TaintsAnnotationKey] = "not json"
+	if taints := nodeTaints(node); taints != "" {
+		t.Errorf("taints from a bad annotation = %q", taints)
+	}
+}
+
+func TestStoreNode(t *testing.T) {
+	store, l := memListing(t, "nodes")
+	heartbeat := time.Date(2017, 6, 1, 8, 30, 0, 0, time.Local)
+	node := fakeapi.Node("gpu-1")
+	node.Status.Capacity[model.ResourceNvidiaGPU] = resource.MustParse("2")
+	node.Status.Allocatable = model.ResourceList{
+		model.ResourceCPU:       resource.MustParse("3500m"),
+		model.ResourceNvidiaGPU: resource.MustParse("1"),
+	}
+	node.Status.Conditions = []model.NodeCondition{
+		{Type: model.NodeReady, Status: model.ConditionTrue, LastHeartbeatTime: unversioned.NewTime(heartbeat)},
+		{Type: model.NodeMemoryPressure, Status: model.ConditionFalse, Reason: "KubeletHasSufficientMemory"},
+	}
+	node.Status.Addresses = append(node.Status.Addresses, model.NodeAddress{Type: model.NodeHostName, Address: "gpu-1"})
+	node.Spec.Unschedulable = true
+	if err := storeNode(node, l); err != nil {
+		t.Fatal(err)
+	}
+	rows := store.Rows("nodes")
+	if len(rows) != 1 {
+		t.Fatalf("%d nodes stored", len(rows))
+	}
+	n := rows[0].(*model.Nodes)
+	if n.Node_name != "gpu-1" || n.Numbers_cpu_core != "4" || n.Numbers_gpu_core != "2" || n.Pod_limit != "110" ||
+		n.Allocatable_cpu != "3500m" || n.Allocatable_gpu != "1" || n.Allocatable_memory != "" ||
+		n.Ready != "True" || !n.Unschedulable || n.Kubelet_version != "v1.5.2" ||
+		n.Addresses != "InternalIP=10.0.0.1,Hostname=gpu-1" {
+		t.Errorf("node = %+v", n)
+	}
+	conditions := store.Rows("node_conditions")
+	if len(conditions) != 2 {
+		t.Fatalf("%d conditions stored", len(conditions))
+	}
+	if c := conditions[0].(*model.NodeConditions); c.Node_name != "gpu-1" || c.Type != "Ready" ||
+		c.Last_heartbeat_time != common.RecordTime(heartbeat) || c.Last_transition_time != "" || c.Tag != "run-1" {
+		t.Errorf("condition = %+v", c)
+	}
+	if c := conditions[1].(*model.NodeConditions); c.Type != "MemoryPressure" || c.Status != "False" ||
+		c.Reason != "KubeletHasSufficientMemory" || c.Last_heartbeat_time != "" {
+		t.Errorf("condition = %+v", c)
+	}
+}
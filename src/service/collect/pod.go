@@ -0,0 +1,70 @@
+package collect
+
+import (
+	model "model/collect"
+)
+
+const (
+	QOSGuaranteed = "Guaranteed"
+	QOSBurstable  = "Burstable"
+	QOSBestEffort = "BestEffort"
+)
+
+var qosResources = []model.ResourceName{model.ResourceCPU, model.ResourceMemory}
+
+// qosClass computes the quality of service class of a pod the way the kubelet
+// does: BestEffort when no container requests or limits cpu or memory,
+// Guaranteed when every container has cpu and memory limits and the requests
+// equal the limits, Burstable otherwise.
+func qosClass(spec model.PodSpec) string {
+	requests := make(model.ResourceList)
+	limits := make(model.ResourceList)
+	guaranteed := true
+	for _, c := range spec.Containers {
+		addQuantities(requests, c.Resources.Requests)
+		found := addQuantities(limits, c.Resources.Limits)
+		if found < len(qosResources) {
+			guaranteed = false
+		}
+	}
+	if len(requests) == 0 && len(limits) == 0 {
+		return QOSBestEffort
+	}
+	if guaranteed && len(requests) == len(limits) {
+		for name, limit := range limits {
+			request, ok := requests[name]
+			if !ok || request.Cmp(limit) != 0 {
+				return QOSBurstable
+			}
+		}
+		return QOSGuaranteed
+	}
+	return QOSBurstable
+}
+
+// addQuantities adds the non-zero cpu and memory quantities of from to sum
+// and returns how many it found.
+func addQuantities(sum model.ResourceList, from model.ResourceList) int {
+	found := 0
+	for _, name := range qosResources {
+		q, ok := from[name]
+		if !ok || q.IsZero() {
+			continue
+		}
+		found++
+		total := sum[name]
+		total.Add(q)
+		sum[name] = total
+	}
+	return found
+}
+
+// controllerRef returns the owner reference of the managing controller.
+func controllerRef(meta model.ObjectMeta) *model.OwnerReference {
+	for i, ref := range meta.OwnerReferences {
+		if ref.Controller != nil && *ref.Controller {
+			return &meta.OwnerReferences[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package collect
+
+import (
+	"common"
+	model "model/collect"
+	"service/collect/fakeapi"
+	"testing"
+
+	"k8s.io/client-go/pkg/api/resource"
+)
+
+func resources(cpu string, memory string) model.ResourceList {
+	list := make(model.ResourceList)
+	if cpu != "" {
+		list[model.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		list[model.ResourceMemory] = resource.MustParse(memory)
+	}
+	return list
+}
+
+func TestQosClass(t *testing.T) {
+	container := func(requests model.ResourceList, limits model.ResourceList) model.Container {
+		return model.Container{Resources: model.ResourceRequirements{Requests: requests, Limits: limits}}
+	}
+	tests := []struct {
+		name       string
+		containers []model.Container
+		want       string
+	}{
+		{"nothing set", []model.Container{container(nil, nil)}, QOSBestEffort},
+		{"zero requests", []model.Container{container(resources("0", "0"), nil)}, QOSBestEffort},
+		{"requests equal limits", []model.Container{
+			container(resources("500m", "128Mi"), resources("500m", "128Mi")),
+			container(resources("1", "1Gi"), resources("1", "1Gi")),
+		}, QOSGuaranteed},
+		{"limits only", []model.Container{container(nil, resources("1", "1Gi"))}, QOSBurstable},
+		{"requests below limits", []model.Container{container(resources("250m", "1Gi"), resources("1", "1Gi"))}, QOSBurstable},
+		{"memory limit missing", []model.Container{container(resources("1", ""), resources("1", ""))}, QOSBurstable},
+		{"one container without limits", []model.Container{
+			container(resources("1", "1Gi"), resources("1", "1Gi")),
+			container(nil, nil),
+		}, QOSBurstable},
+	}
+	for _, test := range tests {
+		if got := qosClass(model.PodSpec{Containers: test.containers}); got != test.want {
+			t.Errorf("%s: qos class %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestControllerRef(t *testing.T) {
+	controller, other := true, false
+	var meta model.ObjectMeta
+	if ref := controllerRef(meta); ref != nil {
+		t.Errorf("controller of an orphan = %+v", ref)
+	}
+	meta.OwnerReferences = []model.OwnerReference{
+		{Kind: "Node", Name: "node-1"},
+		{Kind: "Deployment", Name: "web", Controller: &other},
+		{Kind: "ReplicaSet", Name: "web-1234", UID: "rs-uid", Controller: &controller},
+	}
+	if ref := controllerRef(meta); ref == nil || ref.Kind != "ReplicaSet" || ref.Name != "web-1234" {
+		t.Errorf("controller = %+v", ref)
+	}
+}
+
+func TestStorePod(t *testing.T) {
+	store, l := memListing(t, "pods")
+	controller := true
+	pod := fakeapi.Pod("default", "web-1234-abcde", "node-1")
+	pod.OwnerReferences = []model.OwnerReference{{Kind: "ReplicaSet", Name: "web-1234", UID: "rs-uid", Controller: &controller}}
+	pod.Spec.Containers = append(pod.Spec.Containers, model.Container{Name: "sidecar"})
+	pod.Spec.Containers[0].Resources.Requests = resources("100m", "")
+	pod.Status.ContainerStatuses = []model.ContainerStatus{{Name: "web", RestartCount: 2}, {Name: "sidecar", RestartCount: 1}}
+	if err := storePod(pod, l); err != nil {
+		t.Fatal(err)
+	}
+	rows := store.Rows("pods")
+	if len(rows) != 1 {
+		t.Fatalf("%d pods stored", len(rows))
+	}
+	p := rows[0].(*model.Pods)
+	if p.Namespace != "default" || p.Pod_name != "web-1234-abcde" || p.Uid != "default/web-1234-abcde" ||
+		p.Phase != "Running" || p.Pod_hostIP != "10.0.0.1" || p.Pod_IP != "10.1.0.1" || p.Node_name != "node-1" ||
+		p.Restart_policy != "Always" || p.Qos_class != QOSBurstable {
+		t.Errorf("pod = %+v", p)
+	}
+	if p.Owner_kind != "ReplicaSet" || p.Owner_name != "web-1234" || p.Owner_uid != "rs-uid" {
+		t.Errorf("owner = %s %s %s", p.Owner_kind, p.Owner_name, p.Owner_uid)
+	}
+	if p.Containers_numbers != "2" || p.Restart_count != "3" || p.Start_time != common.RecordTime(fakeapi.Created.Time) {
+		t.Errorf("pod = %+v", p)
+	}
+	if l.Counter("containers") != 2 {
+		t.Errorf("%d containers counted", l.Counter("containers"))
+	}
+}
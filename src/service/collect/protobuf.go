@@ -0,0 +1,520 @@
+package collect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/pkg/api/resource"
+	"k8s.io/client-go/pkg/api/unversioned"
+	"k8s.io/client-go/pkg/runtime"
+)
+
+// The apiserver wraps protobuf objects in a runtime.Unknown message behind a
+// four byte magic number. Objects are decoded by reflection over the
+// protobuf struct tags of model/collect, which are copied from upstream, so
+// no generated code is needed for the hand-copied types.
+const (
+	protobufContentType = "application/vnd.kubernetes.protobuf"
+	protobufMagic       = "k8s\x00"
+)
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errProtobufTruncated = errors.New("protobuf: truncated message")
+
+var (
+	timeType      = reflect.TypeOf(unversioned.Time{})
+	quantityType  = reflect.TypeOf(resource.Quantity{})
+	extensionType = reflect.TypeOf(runtime.RawExtension{})
+)
+
+type protoField struct {
+	index    []int
+	wire     int
+	repeated bool
+}
+
+var (
+	protoFieldsLock sync.Mutex
+	protoFields     = make(map[reflect.Type]map[int]protoField)
+)
+
+// fieldsOf maps the protobuf field numbers of struct type t to its fields.
+func fieldsOf(t reflect.Type) map[int]protoField {
+	protoFieldsLock.Lock()
+	defer protoFieldsLock.Unlock()
+	if fields, ok := protoFields[t]; ok {
+		return fields
+	}
+	fields := make(map[int]protoField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("protobuf")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		if len(parts) < 3 {
+			continue
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		wire := wireBytes
+		switch parts[0] {
+		case "varint", "zigzag32", "zigzag64":
+			wire = wireVarint
+		case "fixed64":
+			wire = wireFixed64
+		case "fixed32":
+			wire = wireFixed32
+		}
+		fields[n] = protoField{index: f.Index, wire: wire, repeated: parts[2] == "rep"}
+	}
+	protoFields[t] = fields
+	return fields
+}
+
+// unwrapProtobuf checks the magic number and returns the raw object of the
+// runtime.Unknown envelope.
+func unwrapProtobuf(body []byte) ([]byte, error) {
+	if !bytes.HasPrefix(body, []byte(protobufMagic)) {
+		return nil, errors.New("protobuf: missing k8s magic number")
+	}
+	var raw []byte
+	err := eachField(body[len(protobufMagic):], func(n int, wire int, v uint64, b []byte) error {
+		if n == 2 && wire == wireBytes {
+			raw = b
+		}
+		return nil
+	})
+	return raw, err
+}
+
+// wrapProtobuf is the inverse of unwrapProtobuf.
+func wrapProtobuf(apiVersion string, kind string, raw []byte) []byte {
+	var typeMeta []byte
+	typeMeta = appendBytesField(typeMeta, 1, []byte(apiVersion))
+	typeMeta = appendBytesField(typeMeta, 2, []byte(kind))
+	out := []byte(protobufMagic)
+	out = appendBytesField(out, 1, typeMeta)
+	out = appendBytesField(out, 2, raw)
+	return out
+}
+
+// eachField calls fn for every field of a message; varint and fixed values
+// are passed in v, length delimited ones in b.
+func eachField(data []byte, fn func(n int, wire int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, k := binary.Uvarint(data)
+		if k <= 0 {
+			return errProtobufTruncated
+		}
+		data = data[k:]
+		n, wire := int(key>>3), int(key&7)
+		var v uint64
+		var b []byte
+		switch wire {
+		case wireVarint:
+			v, k = binary.Uvarint(data)
+			if k <= 0 {
+				return errProtobufTruncated
+			}
+			data = data[k:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errProtobufTruncated
+			}
+			v = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errProtobufTruncated
+			}
+			v = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			l, k := binary.Uvarint(data)
+			if k <= 0 || uint64(len(data)-k) < l {
+				return errProtobufTruncated
+			}
+			b = data[k : k+int(l)]
+			data = data[k+int(l):]
+		default:
+			return fmt.Errorf("protobuf: unsupported wire type %d", wire)
+		}
+		if err := fn(n, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalProtobuf decodes a message into the struct v points to. Fields
+// without a protobuf tag, and unknown field numbers, are skipped; a known
+// field sent with another wire type than its tag declares fails the decode.
+func unmarshalProtobuf(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("protobuf: decode target must be a non-nil pointer")
+	}
+	return decodeMessage(data, rv.Elem())
+}
+
+func decodeMessage(data []byte, v reflect.Value) error {
+	switch v.Type() {
+	case timeType:
+		return decodeTime(data, v)
+	case quantityType:
+		return decodeQuantity(data, v)
+	case extensionType:
+		return eachField(data, func(n int, wire int, _ uint64, b []byte) error {
+			if n == 1 && wire == wireBytes {
+				v.FieldByName("Raw").SetBytes(append([]byte(nil), b...))
+			}
+			return nil
+		})
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("protobuf: cannot decode a message into %s", v.Type())
+	}
+	fields := fieldsOf(v.Type())
+	return eachField(data, func(n int, wire int, x uint64, b []byte) error {
+		f, ok := fields[n]
+		if !ok {
+			return nil
+		}
+		// proto2 repeated scalars may come packed
+		if wire != f.wire && !(f.repeated && wire == wireBytes && f.wire != wireBytes) {
+			return wireMismatch(v.Type(), n, wire, f.wire)
+		}
+		fv := v.FieldByIndex(f.index)
+		if f.repeated && fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			return decodeRepeated(fv, wire, x, b)
+		}
+		if fv.Kind() == reflect.Map {
+			return decodeMapEntry(fv, b)
+		}
+		return decodeValue(fv, wire, x, b)
+	})
+}
+
+func decodeRepeated(fv reflect.Value, wire int, x uint64, b []byte) error {
+	elem := fv.Type().Elem()
+	// proto2 repeated scalars are not packed, but accept packed ones too
+	if wire == wireBytes && isScalar(elem) {
+		for len(b) > 0 {
+			x, k := binary.Uvarint(b)
+			if k <= 0 {
+				return errProtobufTruncated
+			}
+			b = b[k:]
+			e := reflect.New(elem).Elem()
+			if err := decodeValue(e, wireVarint, x, nil); err != nil {
+				return err
+			}
+			fv.Set(reflect.Append(fv, e))
+		}
+		return nil
+	}
+	e := reflect.New(elem).Elem()
+	if err := decodeValue(e, wire, x, b); err != nil {
+		return err
+	}
+	fv.Set(reflect.Append(fv, e))
+	return nil
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// decodeMapEntry decodes one map entry message, key 1 and value 2.
+func decodeMapEntry(fv reflect.Value, b []byte) error {
+	t := fv.Type()
+	if fv.IsNil() {
+		fv.Set(reflect.MakeMap(t))
+	}
+	key := reflect.New(t.Key()).Elem()
+	value := reflect.New(t.Elem()).Elem()
+	err := eachField(b, func(n int, wire int, x uint64, b []byte) error {
+		var e reflect.Value
+		switch n {
+		case 1:
+			e = key
+		case 2:
+			e = value
+		default:
+			return nil
+		}
+		if want := wireOf(e.Type()); wire != want {
+			return wireMismatch(t, n, wire, want)
+		}
+		return decodeValue(e, wire, x, b)
+	})
+	if err != nil {
+		return err
+	}
+	fv.SetMapIndex(key, value)
+	return nil
+}
+
+func decodeValue(v reflect.Value, wire int, x uint64, b []byte) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		e := reflect.New(v.Type().Elem())
+		if err := decodeValue(e.Elem(), wire, x, b); err != nil {
+			return err
+		}
+		v.Set(e)
+	case reflect.Struct:
+		return decodeMessage(b, v)
+	case reflect.String:
+		v.SetString(string(b))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("protobuf: unexpected %s", v.Type())
+		}
+		v.SetBytes(append([]byte(nil), b...))
+	case reflect.Bool:
+		v.SetBool(x != 0)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(x))
+	case reflect.Uint32, reflect.Uint64:
+		v.SetUint(x)
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(x))
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(uint32(x))))
+	default:
+		return fmt.Errorf("protobuf: unsupported %s", v.Type())
+	}
+	return nil
+}
+
+func decodeTime(data []byte, v reflect.Value) error {
+	var seconds, nanos int64
+	err := eachField(data, func(n int, wire int, x uint64, _ []byte) error {
+		if (n == 1 || n == 2) && wire != wireVarint {
+			return wireMismatch(timeType, n, wire, wireVarint)
+		}
+		switch n {
+		case 1:
+			seconds = int64(x)
+		case 2:
+			nanos = int64(int32(x))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(unversioned.Time{Time: time.Unix(seconds, nanos).Local()}))
+	return nil
+}
+
+func decodeQuantity(data []byte, v reflect.Value) error {
+	var s string
+	err := eachField(data, func(n int, wire int, _ uint64, b []byte) error {
+		if n != 1 {
+			return nil
+		}
+		if wire != wireBytes {
+			return wireMismatch(quantityType, n, wire, wireBytes)
+		}
+		s = string(b)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(q))
+	return nil
+}
+
+func wireMismatch(t reflect.Type, n int, wire int, want int) error {
+	return fmt.Errorf("protobuf: field %d of %s has wire type %d, want %d", n, t, wire, want)
+}
+
+// marshalProtobuf encodes the struct v points to with the same rules
+// unmarshalProtobuf decodes it. It is used to serve protobuf in tests.
+func marshalProtobuf(v interface{}) []byte {
+	return encodeMessage(nil, reflect.Indirect(reflect.ValueOf(v)))
+}
+
+func encodeMessage(out []byte, v reflect.Value) []byte {
+	switch v.Type() {
+	case timeType:
+		t := v.Interface().(unversioned.Time)
+		if t.IsZero() {
+			return out
+		}
+		out = appendVarintField(out, 1, uint64(t.Unix()))
+		return appendVarintField(out, 2, uint64(t.Nanosecond()))
+	case quantityType:
+		q := v.Interface().(resource.Quantity)
+		return appendBytesField(out, 1, []byte(q.String()))
+	case extensionType:
+		return appendBytesField(out, 1, v.FieldByName("Raw").Bytes())
+	}
+	fields := fieldsOf(v.Type())
+	numbers := make([]int, 0, len(fields))
+	for n := range fields {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	for _, n := range numbers {
+		f := fields[n]
+		fv := v.FieldByIndex(f.index)
+		switch {
+		case fv.Kind() == reflect.Map:
+			keys := fv.MapKeys()
+			sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+			for _, k := range keys {
+				var entry []byte
+				entry = encodeValue(entry, 1, wireOf(k.Type()), k)
+				entry = encodeValue(entry, 2, wireOf(fv.Type().Elem()), fv.MapIndex(k))
+				out = appendBytesField(out, n, entry)
+			}
+		case f.repeated && fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8:
+			for i := 0; i < fv.Len(); i++ {
+				out = encodeValue(out, n, f.wire, fv.Index(i))
+			}
+		default:
+			out = encodeValue(out, n, f.wire, fv)
+		}
+	}
+	return out
+}
+
+func wireOf(t reflect.Type) int {
+	if isScalar(t) {
+		return wireVarint
+	}
+	return wireBytes
+}
+
+func encodeValue(out []byte, n int, wire int, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return out
+		}
+		// a set optional field is sent even when it holds the zero value
+		e := v.Elem()
+		switch e.Kind() {
+		case reflect.Bool:
+			x := uint64(0)
+			if e.Bool() {
+				x = 1
+			}
+			return appendVarintField(out, n, x)
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			return appendVarintField(out, n, uint64(e.Int()))
+		case reflect.String:
+			return appendBytesField(out, n, []byte(e.String()))
+		}
+		return encodeValue(out, n, wire, e)
+	case reflect.Struct:
+		return appendBytesField(out, n, encodeMessage(nil, v))
+	case reflect.String:
+		if v.Len() == 0 {
+			return out
+		}
+		return appendBytesField(out, n, []byte(v.String()))
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return out
+		}
+		return appendBytesField(out, n, v.Bytes())
+	case reflect.Bool:
+		if !v.Bool() {
+			return out
+		}
+		return appendVarintField(out, n, 1)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if v.Int() == 0 {
+			return out
+		}
+		return appendVarintField(out, n, uint64(v.Int()))
+	case reflect.Uint32, reflect.Uint64:
+		if v.Uint() == 0 {
+			return out
+		}
+		return appendVarintField(out, n, v.Uint())
+	}
+	return out
+}
+
+func appendUvarint(out []byte, x uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(out, buf[:binary.PutUvarint(buf[:], x)]...)
+}
+
+func appendVarintField(out []byte, n int, x uint64) []byte {
+	out = appendUvarint(out, uint64(n)<<3|wireVarint)
+	return appendUvarint(out, x)
+}
+
+func appendBytesField(out []byte, n int, b []byte) []byte {
+	out = appendUvarint(out, uint64(n)<<3|wireBytes)
+	out = appendUvarint(out, uint64(len(b)))
+	return append(out, b...)
+}
+
+// protoListMeta is unversioned.ListMeta with the continue token of later
+// releases.
+type protoListMeta struct {
+	ResourceVersion string `protobuf:"bytes,2,opt,name=resourceVersion"`
+	Continue        string `protobuf:"bytes,3,opt,name=continue"`
+}
+
+// decodeProtobufList decodes a protobuf list response, handing every item to
+// each, and returns the continue token.
+func decodeProtobufList(body []byte, newItem func() interface{}, each func(item interface{}) error) (string, error) {
+	raw, err := unwrapProtobuf(body)
+	if err != nil {
+		return "", err
+	}
+	var meta protoListMeta
+	err = eachField(raw, func(n int, wire int, _ uint64, b []byte) error {
+		if wire != wireBytes {
+			return nil
+		}
+		switch n {
+		case 1:
+			return unmarshalProtobuf(b, &meta)
+		case 2:
+			item := newItem()
+			if err := unmarshalProtobuf(b, item); err != nil {
+				return err
+			}
+			return each(item)
+		}
+		return nil
+	})
+	return meta.Continue, err
+}
@@ -0,0 +1,210 @@
+package collect
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	model "model/collect"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/pkg/api/resource"
+	"k8s.io/client-go/pkg/api/unversioned"
+	"k8s.io/client-go/pkg/types"
+)
+
+func testPod(i int) model.Pod {
+	controller := true
+	grace := int64(30)
+	var pod model.Pod
+	pod.Name = fmt.Sprintf("web-%d", i)
+	pod.Namespace = "default"
+	pod.UID = types.UID(fmt.Sprintf("uid-%d", i))
+	pod.CreationTimestamp = unversioned.NewTime(time.Date(2017, 3, 1, 10, 0, i%60, 0, time.Local))
+	pod.Labels = map[string]string{"app": "web", "tier": "front"}
+	pod.OwnerReferences = []model.OwnerReference{{APIVersion: "extensions/v1beta1", Kind: "ReplicaSet",
+		Name: "web-1234", UID: "rs-uid", Controller: &controller}}
+	pod.Spec.NodeName = "node-1"
+	pod.Spec.TerminationGracePeriodSeconds = &grace
+	pod.Spec.Containers = []model.Container{{Name: "web", Image: "nginx:1.11",
+		Resources: model.ResourceRequirements{Limits: model.ResourceList{
+			model.ResourceCPU:    resource.MustParse("500m"),
+			model.ResourceMemory: resource.MustParse("128Mi"),
+		}}}}
+	pod.Status.Phase = model.PodRunning
+	pod.Status.PodIP = "10.1.2.3"
+	start := unversioned.NewTime(pod.CreationTimestamp.Add(time.Second))
+	pod.Status.StartTime = &start
+	pod.Status.ContainerStatuses = []model.ContainerStatus{{Name: "web", Ready: true, RestartCount: 2}}
+	return pod
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	pod := testPod(7)
+	var got model.Pod
+	if err := unmarshalProtobuf(marshalProtobuf(&pod), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != pod.Name || got.UID != pod.UID || !reflect.DeepEqual(got.Labels, pod.Labels) {
+		t.Errorf("metadata = %+v", got.ObjectMeta)
+	}
+	if !got.CreationTimestamp.Equal(pod.CreationTimestamp) || got.Status.StartTime == nil ||
+		!got.Status.StartTime.Equal(*pod.Status.StartTime) {
+		t.Errorf("times = %v %v", got.CreationTimestamp, got.Status.StartTime)
+	}
+	if len(got.OwnerReferences) != 1 || got.OwnerReferences[0].Controller == nil || !*got.OwnerReferences[0].Controller {
+		t.Errorf("owner references = %+v", got.OwnerReferences)
+	}
+	if got.Spec.TerminationGracePeriodSeconds == nil || *got.Spec.TerminationGracePeriodSeconds != 30 {
+		t.Errorf("grace period = %v", got.Spec.TerminationGracePeriodSeconds)
+	}
+	limits := got.Spec.Containers[0].Resources.Limits
+	if cpu := limits[model.ResourceCPU]; cpu.String() != "500m" {
+		t.Errorf("cpu limit = %s", cpu.String())
+	}
+	if mem := limits[model.ResourceMemory]; mem.String() != "128Mi" {
+		t.Errorf("memory limit = %s", mem.String())
+	}
+	if got.Status.Phase != model.PodRunning || got.Status.ContainerStatuses[0].RestartCount != 2 ||
+		!got.Status.ContainerStatuses[0].Ready {
+		t.Errorf("status = %+v", got.Status)
+	}
+}
+
+func TestDecodeProtobufList(t *testing.T) {
+	var list []byte
+	list = appendBytesField(list, 1, marshalProtobuf(&protoListMeta{ResourceVersion: "10", Continue: "next-page"}))
+	for i := 0; i < 3; i++ {
+		pod := testPod(i)
+		list = appendBytesField(list, 2, marshalProtobuf(&pod))
+	}
+	var names []string
+	token, err := decodeProtobufList(wrapProtobuf("v1", "PodList", list), func() interface{} { return new(model.Pod) }, func(item interface{}) error {
+		names = append(names, item.(*model.Pod).Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "next-page" || fmt.Sprint(names) != "[web-0 web-1 web-2]" {
+		t.Errorf("continue = %q, items = %v", token, names)
+	}
+	if _, err := decodeProtobufList(list, func() interface{} { return new(model.Pod) }, func(interface{}) error { return nil }); err == nil {
+		t.Error("a body without the magic number should fail")
+	}
+	if _, err := decodeProtobufList(wrapProtobuf("v1", "PodList", list[:len(list)-5]), func() interface{} { return new(model.Pod) }, func(interface{}) error { return nil }); err == nil {
+		t.Error("a truncated list should fail")
+	}
+}
+
+// apiserverPodList is a PodList the way the apiserver sends it, laid out by
+// hand from the field numbers of upstream generated.proto: every string of
+// a non-nullable field is sent even when empty, fields in number order.
+const apiserverPodList = "" +
+	"6b3873000a0d0a0276311207506f644c69737412cf010a130a0c2f6170692f76" +
+	"312f706f6473120331303012b7010a5c0a057765622d3012001a076465666175" +
+	"6c7422252f6170692f76312f6e616d657370616365732f64656661756c742f70" +
+	"6f64732f7765622d302a057569642d30320234323800420608a0b7dac5055a0a" +
+	"0a0361707012037765627a00122512110a03776562120a6e67696e783a312e31" +
+	"311a06416c77617973201e52066e6f64652d311a300a0752756e6e696e672a08" +
+	"31302e302e302e31320831302e312e322e333a0608a1b7dac50542090a037765" +
+	"62200128021a002200"
+
+func TestDecodeApiserverPodList(t *testing.T) {
+	body, err := hex.DecodeString(apiserverPodList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pods []*model.Pod
+	_, err = decodeProtobufList(body, func() interface{} { return new(model.Pod) }, func(item interface{}) error {
+		pods = append(pods, item.(*model.Pod))
+		return nil
+	})
+	if err != nil || len(pods) != 1 {
+		t.Fatalf("decoded %d pods: %v", len(pods), err)
+	}
+	p := pods[0]
+	if p.Name != "web-0" || p.Namespace != "default" || p.UID != "uid-0" || p.ResourceVersion != "42" || p.Labels["app"] != "web" {
+		t.Errorf("metadata = %+v", p.ObjectMeta)
+	}
+	if p.CreationTimestamp.Unix() != 1488362400 || p.Status.StartTime == nil || p.Status.StartTime.Unix() != 1488362401 {
+		t.Errorf("times = %v %v", p.CreationTimestamp, p.Status.StartTime)
+	}
+	if p.Spec.NodeName != "node-1" || p.Spec.RestartPolicy != model.RestartPolicyAlways || len(p.Spec.Containers) != 1 ||
+		p.Spec.Containers[0].Image != "nginx:1.11" || p.Spec.TerminationGracePeriodSeconds == nil || *p.Spec.TerminationGracePeriodSeconds != 30 {
+		t.Errorf("spec = %+v", p.Spec)
+	}
+	if p.Status.Phase != model.PodRunning || p.Status.HostIP != "10.0.0.1" || p.Status.PodIP != "10.1.2.3" ||
+		len(p.Status.ContainerStatuses) != 1 || !p.Status.ContainerStatuses[0].Ready || p.Status.ContainerStatuses[0].RestartCount != 2 {
+		t.Errorf("status = %+v", p.Status)
+	}
+}
+
+func TestDecodeWireMismatch(t *testing.T) {
+	var meta model.ObjectMeta
+	// generation, a varint, sent length delimited
+	if err := unmarshalProtobuf(appendBytesField(nil, 7, []byte("1")), &meta); err == nil {
+		t.Error("a length delimited generation decoded")
+	}
+	// name, a string, sent as a varint
+	if err := unmarshalProtobuf(appendVarintField(nil, 1, 5), &meta); err == nil {
+		t.Error("a varint name decoded")
+	}
+	var q resource.Quantity
+	truncated := appendBytesField(nil, 1, []byte("500m"))
+	err := decodeQuantity(truncated[:len(truncated)-2], reflect.ValueOf(&q).Elem())
+	if err != errProtobufTruncated {
+		t.Errorf("a truncated quantity decoded with %v", err)
+	}
+}
+
+// benchmarkPods is the size of the pod list the benchmarks decode, a
+// cluster of a few hundred nodes.
+const benchmarkPods = 5000
+
+func benchmarkPodList() *model.PodList {
+	list := &model.PodList{}
+	for i := 0; i < benchmarkPods; i++ {
+		list.Items = append(list.Items, testPod(i))
+	}
+	return list
+}
+
+func BenchmarkDecodePodListJSON(b *testing.B) {
+	body, err := json.Marshal(benchmarkPodList())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		_, err := decodeList(bytes.NewReader(body), func() interface{} { return new(model.Pod) }, func(interface{}) error {
+			n++
+			return nil
+		}, "")
+		if err != nil || n != benchmarkPods {
+			b.Fatal(n, err)
+		}
+	}
+}
+
+func BenchmarkDecodePodListProtobuf(b *testing.B) {
+	body := wrapProtobuf("v1", "PodList", marshalProtobuf(benchmarkPodList()))
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		_, err := decodeProtobufList(body, func() interface{} { return new(model.Pod) }, func(interface{}) error {
+			n++
+			return nil
+		})
+		if err != nil || n != benchmarkPods {
+			b.Fatal(n, err)
+		}
+	}
+}
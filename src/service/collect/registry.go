@@ -0,0 +1,327 @@
+package collect
+
+import (
+	"common"
+	"fmt"
+	model "model/collect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Collector collects one resource kind. Resource, whose objects are of
+// ObjectKind, is listed from the first of GroupVersions, most preferred
+// first, that the cluster serves. The items of the list are decoded one at a
+// time into the value NewItem returns and handed to Store; Finish, when set,
+// runs once all items are stored.
+// Inventory keeps the inventory table of Kind, swept once the whole list was
+// stored. Namespaced collectors only list the namespaces of the shard when
+// the collection is sharded. JSONOnly collectors never ask for protobuf. A
+// collector with Every set is only run once that much time has passed since
+// its last successful collection.
+type Collector struct {
+	Kind          string
+	Resource      string
+	ObjectKind    string
+	GroupVersions []string
+	Namespaced    bool
+	NewItem       func() interface{}
+	JSONOnly      bool
+	Store         func(item interface{}, l *Listing) error
+	Finish        func(l *Listing) error
+	Inventory     bool
+	Every         time.Duration
+	Enabled       bool
+}
+
+// Listing is the state of one collector across the items, and pages, of a
+// list.
+type Listing struct {
+	Run   *model.Runs
+	Kind  string
+	Items int
+	// Namespaces is the shard the listing covers, nil for all.
+	Namespaces []string
+	counters   map[string]int
+	inv        *inventory
+	// clock, when set, is the time of the rows instead of now, for a dump
+	// taken earlier.
+	clock func() time.Time
+}
+
+// recordTime is the record time of the rows stored now.
+func (l *Listing) recordTime() string {
+	if l.clock != nil {
+		return common.RecordTime(l.clock())
+	}
+	return common.RecordNow()
+}
+
+// Touch marks an object as seen in the inventory of the listing's kind.
+func (l *Listing) Touch(meta model.ObjectMeta) {
+	if l.inv != nil {
+		l.inv.touch(meta)
+	}
+}
+
+// Add adds n to a named counter, for totals a Finish hook needs.
+func (l *Listing) Add(counter string, n int) {
+	if l.counters == nil {
+		l.counters = make(map[string]int)
+	}
+	l.counters[counter] += n
+}
+
+func (l *Listing) Counter(counter string) int {
+	return l.counters[counter]
+}
+
+var (
+	registryLock sync.Mutex
+	collectors   []*Collector
+	lastCollect  = make(map[string]time.Time)
+	lastResults  []model.RunResults
+)
+
+// Register adds a collector. Registering a kind twice replaces the first one.
+func Register(c *Collector) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for i, old := range collectors {
+		if old.Kind == c.Kind {
+			collectors[i] = c
+			return
+		}
+	}
+	collectors = append(collectors, c)
+}
+
+func init() {
+	Register(&Collector{Kind: "pods", Resource: "pods", ObjectKind: "Pod", Namespaced: true, GroupVersions: []string{"v1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.Pod) }, Store: storePod, Finish: finishPods, Inventory: true})
+	Register(&Collector{Kind: "nodes", Resource: "nodes", ObjectKind: "Node", GroupVersions: []string{"v1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.Node) }, Store: storeNode, Inventory: true})
+	Register(&Collector{Kind: "services", Resource: "services", ObjectKind: "Service", Namespaced: true, GroupVersions: []string{"v1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.Service) }, Store: storeService, Finish: finishServices, Inventory: true})
+	Register(&Collector{Kind: "endpoints", Resource: "endpoints", ObjectKind: "Endpoints", Namespaced: true, GroupVersions: []string{"v1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.Endpoints) }, Store: storeEndpoints})
+	Register(&Collector{Kind: "namespaces", Resource: "namespaces", ObjectKind: "Namespace", GroupVersions: []string{"v1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.Namespace) }, Store: storeNamespace})
+	Register(&Collector{Kind: "resourcequotas", Resource: "resourcequotas", ObjectKind: "ResourceQuota", Namespaced: true, GroupVersions: []string{"v1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.ResourceQuota) }, Store: storeResourceQuota})
+	Register(&Collector{Kind: "limitranges", Resource: "limitranges", ObjectKind: "LimitRange", Namespaced: true, GroupVersions: []string{"v1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.LimitRange) }, Store: storeLimitRange})
+	Register(&Collector{Kind: "persistentvolumes", Resource: "persistentvolumes", ObjectKind: "PersistentVolume", GroupVersions: []string{"v1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.PersistentVolume) }, Store: storePersistentVolume})
+	Register(&Collector{Kind: "persistentvolumeclaims", Resource: "persistentvolumeclaims", ObjectKind: "PersistentVolumeClaim", Namespaced: true, GroupVersions: []string{"v1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.PersistentVolumeClaim) }, Store: storePersistentVolumeClaim})
+	Register(&Collector{Kind: "replicationcontrollers", Resource: "replicationcontrollers", ObjectKind: "ReplicationController", Namespaced: true, GroupVersions: []string{"v1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.ReplicationController) }, Store: storeReplicationController})
+	Register(&Collector{Kind: "deployments", Resource: "deployments", ObjectKind: "Deployment", Namespaced: true, GroupVersions: []string{"apps/v1", "apps/v1beta2", "apps/v1beta1", "extensions/v1beta1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.Deployment) }, Store: storeDeployment})
+	Register(&Collector{Kind: "replicasets", Resource: "replicasets", ObjectKind: "ReplicaSet", Namespaced: true, GroupVersions: []string{"apps/v1", "apps/v1beta2", "extensions/v1beta1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.ReplicaSet) }, Store: storeReplicaSet})
+	Register(&Collector{Kind: "daemonsets", Resource: "daemonsets", ObjectKind: "DaemonSet", Namespaced: true, GroupVersions: []string{"apps/v1", "apps/v1beta2", "extensions/v1beta1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.DaemonSet) }, Store: storeDaemonSet})
+	Register(&Collector{Kind: "statefulsets", Resource: "statefulsets", ObjectKind: "StatefulSet", Namespaced: true, GroupVersions: []string{"apps/v1", "apps/v1beta2", "apps/v1beta1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.StatefulSet) }, Store: storeStatefulSet})
+	Register(&Collector{Kind: "jobs", Resource: "jobs", ObjectKind: "Job", Namespaced: true, GroupVersions: []string{"batch/v1"}, Enabled: true,
+		NewItem: func() interface{} { return new(model.Job) }, Store: storeJob})
+}
+
+// Configure applies a comma separated list of kinds. A plain kind enables it,
+// a kind prefixed with - disables it; when any kind is listed plainly all
+// other kinds are disabled. An empty spec leaves the registry as it is.
+func Configure(spec string) error {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	enable := make(map[string]bool)
+	only := false
+	for _, kind := range strings.Split(spec, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		on := !strings.HasPrefix(kind, "-")
+		kind = strings.TrimPrefix(kind, "-")
+		if findCollector(kind) == nil {
+			return fmt.Errorf("unknown collector %q", kind)
+		}
+		enable[kind] = on
+		only = only || on
+	}
+	for _, c := range collectors {
+		if on, ok := enable[c.Kind]; ok {
+			c.Enabled = on
+		} else if only {
+			c.Enabled = false
+		}
+	}
+	return nil
+}
+
+// SetEvery sets the collection interval of kind.
+func SetEvery(kind string, every time.Duration) error {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	c := findCollector(kind)
+	if c == nil {
+		return fmt.Errorf("unknown collector %q", kind)
+	}
+	c.Every = every
+	return nil
+}
+
+func findCollector(kind string) *Collector {
+	for _, c := range collectors {
+		if c.Kind == kind {
+			return c
+		}
+	}
+	return nil
+}
+
+// collectAll runs every enabled collector that is due, concurrently, and
+// returns one result per registered collector.
+func collectAll(run *model.Runs, d *Discovery) []model.RunResults {
+	registryLock.Lock()
+	list := make([]Collector, len(collectors))
+	for i, c := range collectors {
+		list[i] = *c
+	}
+	registryLock.Unlock()
+
+	var namespaces, known []string
+	var shardErr error
+	if CurrentShard != nil {
+		namespaces, known, shardErr = shardNamespaces()
+		// the objects of deleted namespaces belong to no shard, the
+		// owner of the cluster-scoped kinds sweeps them
+		if !CurrentShard.OwnsClusterScoped() {
+			known = nil
+		}
+	}
+	now := time.Now()
+	results := make([]model.RunResults, len(list))
+	for i := range list {
+		c := &list[i]
+		results[i] = model.RunResults{Run_id: run.Id, Tag: run.Tag, Kind: c.Kind}
+		switch {
+		case !c.Enabled:
+			results[i].Status = model.CollectorDisabled
+		case c.Every > 0 && now.Sub(lastCollected(c.Kind)) < c.Every:
+			results[i].Status = model.CollectorSkipped
+		default:
+			path, ok := resourcePath(d, c.GroupVersions, c.Resource)
+			if !ok {
+				results[i].Status = model.CollectorUnsupported
+				continue
+			}
+			paths := []string{path}
+			var owned, orphans []string
+			if CurrentShard != nil {
+				switch {
+				case c.Namespaced && shardErr != nil:
+					results[i].Status = model.CollectorFailed
+					results[i].Error = shardErr.Error()
+					continue
+				case c.Namespaced:
+					owned, orphans = namespaces, known
+					paths = paths[:0]
+					for _, ns := range namespaces {
+						paths = append(paths, namespacedPath(path, ns))
+					}
+				case !CurrentShard.OwnsClusterScoped():
+					results[i].Status = model.CollectorOtherShard
+					continue
+				}
+			}
+			ThreadCountGet.Add(1)
+			go c.collect(run, paths, owned, orphans, &results[i])
+		}
+	}
+	ThreadCountGet.Wait()
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for _, r := range results {
+		if r.Status == model.CollectorOk {
+			lastCollect[r.Kind] = now
+		}
+	}
+	lastResults = results
+	return results
+}
+
+func lastCollected(kind string) time.Time {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	return lastCollect[kind]
+}
+
+func (c *Collector) collect(run *model.Runs, paths []string, namespaces []string, known []string, result *model.RunResults) {
+	defer ThreadCountGet.Done()
+	start := time.Now()
+	var stats listStats
+	n, err := c.gain(run, paths, namespaces, known, &stats)
+	result.Items = n
+	result.Bytes = stats.Bytes
+	result.Encoding = stats.Encoding
+	result.Duration_ms = int64(time.Since(start) / time.Millisecond)
+	if err != nil {
+		common.LogErr(err)
+		result.Status = model.CollectorFailed
+		result.Error = err.Error()
+		return
+	}
+	result.Status = model.CollectorOk
+}
+
+// gain streams the lists at paths into Store, namespaces being the shard
+// they cover. The inventory is only swept when every item was stored; the
+// objects of namespaces not in known are swept too, unless it is nil.
+func (c *Collector) gain(run *model.Runs, paths []string, namespaces []string, known []string, stats *listStats) (int, error) {
+	l := &Listing{Run: run, Kind: c.Kind, Namespaces: namespaces}
+	if c.Inventory {
+		l.inv = newInventory(c.Kind, run, namespaces, known)
+	}
+	var failed error
+	for _, path := range paths {
+		err := streamList(path, UseProtobuf && !c.JSONOnly, c.NewItem, func(item interface{}) error {
+			l.Items++
+			if err := c.Store(item, l); err != nil && failed == nil {
+				failed = err
+			}
+			return nil
+		}, stats)
+		if err != nil {
+			return l.Items, err
+		}
+	}
+	if failed != nil {
+		return l.Items, failed
+	}
+	if c.Finish != nil {
+		if err := c.Finish(l); err != nil {
+			return l.Items, err
+		}
+	}
+	if l.inv != nil {
+		if err := l.inv.sweep(); err != nil {
+			return l.Items, err
+		}
+	}
+	common.DebugPrint(c.Kind, "is insert", l.Items)
+	return l.Items, nil
+}
+
+// LastResults returns the collector results of the latest run, sorted by
+// kind.
+func LastResults() []model.RunResults {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	results := append([]model.RunResults(nil), lastResults...)
+	sort.Slice(results, func(i, j int) bool { return results[i].Kind < results[j].Kind })
+	return results
+}
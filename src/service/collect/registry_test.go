@@ -0,0 +1,136 @@
+package collect
+
+import (
+	"common"
+	"dao"
+	"errors"
+	model "model/collect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useCollectors replaces the registry with list for the test.
+func useCollectors(t *testing.T, list ...*Collector) {
+	registryLock.Lock()
+	saved, savedLast := collectors, lastCollect
+	collectors, lastCollect = list, make(map[string]time.Time)
+	registryLock.Unlock()
+	t.Cleanup(func() {
+		registryLock.Lock()
+		collectors, lastCollect = saved, savedLast
+		registryLock.Unlock()
+	})
+}
+
+func enabledKinds() string {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	var kinds []string
+	for _, c := range collectors {
+		if c.Enabled {
+			kinds = append(kinds, c.Kind)
+		}
+	}
+	return strings.Join(kinds, ",")
+}
+
+func TestConfigure(t *testing.T) {
+	useCollectors(t, &Collector{Kind: "pods", Enabled: true}, &Collector{Kind: "nodes", Enabled: true}, &Collector{Kind: "events", Enabled: true})
+	if err := Configure(""); err != nil || enabledKinds() != "pods,nodes,events" {
+		t.Fatalf("enabled %s, %v", enabledKinds(), err)
+	}
+	if err := Configure("-events"); err != nil || enabledKinds() != "pods,nodes" {
+		t.Fatalf("enabled %s, %v", enabledKinds(), err)
+	}
+	// listing a kind plainly disables the ones not listed
+	if err := Configure(" nodes, events "); err != nil || enabledKinds() != "nodes,events" {
+		t.Fatalf("enabled %s, %v", enabledKinds(), err)
+	}
+	if err := Configure("pods,deployments"); err == nil || enabledKinds() != "nodes,events" {
+		t.Errorf("enabled %s after an unknown kind, %v", enabledKinds(), err)
+	}
+	if err := SetEvery("deployments", time.Minute); err == nil {
+		t.Error("set the interval of an unknown kind")
+	}
+}
+
+func TestCollectAll(t *testing.T) {
+	_, store := fakeCluster(t)
+	finished := -1
+	useCollectors(t,
+		&Collector{Kind: "pods", Resource: "pods", Namespaced: true, GroupVersions: []string{"v1"}, Enabled: true, Inventory: true,
+			NewItem: func() interface{} { return new(model.Pod) }, Store: storePod,
+			Finish: func(l *Listing) error {
+				finished = l.Items
+				return nil
+			}},
+		&Collector{Kind: "services", Resource: "services", Namespaced: true, GroupVersions: []string{"v1"}, Enabled: true, Inventory: true,
+			NewItem: func() interface{} { return new(model.Service) },
+			Store:   func(item interface{}, l *Listing) error { return errors.New("row rejected") },
+			Finish: func(l *Listing) error {
+				t.Error("finished a collector whose rows were rejected")
+				return nil
+			}},
+		&Collector{Kind: "nodes", Resource: "nodes", GroupVersions: []string{"v1"},
+			NewItem: func() interface{} { return new(model.Node) }, Store: storeNode},
+		&Collector{Kind: "jobs", Resource: "jobs", Namespaced: true, GroupVersions: []string{"batch/v1"}, Enabled: true,
+			NewItem: func() interface{} { return new(model.Job) }, Store: storeJob},
+	)
+	earlier := common.RecordTime(time.Now().Add(-time.Hour))
+	for _, table := range []string{"pod_inventory", "service_inventory"} {
+		if err := dao.Db_upsertInventory(table, "gone-uid", "default", "gone", earlier, "run-0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := &model.Runs{Id: 1, Tag: "run-1", Start_time: common.RecordNow()}
+	results := collectAll(run, CurrentDiscovery())
+	want := []string{model.CollectorOk, model.CollectorFailed, model.CollectorDisabled, model.CollectorUnsupported}
+	for i, r := range results {
+		if r.Status != want[i] || r.Run_id != 1 || r.Tag != "run-1" {
+			t.Errorf("result %d = %+v, want %s", i, r, want[i])
+		}
+	}
+	if results[0].Items != 3 || finished != 3 {
+		t.Errorf("%d pods collected, %d finished", results[0].Items, finished)
+	}
+	if results[1].Error != "row rejected" {
+		t.Errorf("services = %+v", results[1])
+	}
+	if deleted := deletedInventory(store, "pod_inventory"); len(deleted) != 1 || deleted[0] != "gone" {
+		t.Errorf("deleted pods = %v", deleted)
+	}
+	if deleted := deletedInventory(store, "service_inventory"); len(deleted) != 0 {
+		t.Errorf("deleted services = %v, swept after a rejected row", deleted)
+	}
+
+	// a kind collected less often is skipped until it is due
+	if err := SetEvery("pods", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	results = collectAll(&model.Runs{Id: 2, Tag: "run-2", Start_time: common.RecordNow()}, CurrentDiscovery())
+	if results[0].Status != model.CollectorSkipped || results[1].Status != model.CollectorFailed {
+		t.Errorf("results = %+v", results)
+	}
+
+	// a replica without the cluster-scoped kinds leaves them to the others
+	defer func() { CurrentShard = nil }()
+	CurrentShard = staticShard{"default": true}
+	if err := SetEvery("pods", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := Configure("pods,nodes"); err != nil {
+		t.Fatal(err)
+	}
+	results = collectAll(&model.Runs{Id: 3, Tag: "run-3", Start_time: common.RecordNow()}, CurrentDiscovery())
+	want = []string{model.CollectorOk, model.CollectorDisabled, model.CollectorOtherShard, model.CollectorDisabled}
+	for i, r := range results {
+		if r.Status != want[i] {
+			t.Errorf("result %d = %+v, want %s", i, r, want[i])
+		}
+	}
+	if results[0].Items != 2 {
+		t.Errorf("%d pods collected by the shard of default", results[0].Items)
+	}
+}
@@ -0,0 +1,101 @@
+package collect
+
+import (
+	model "model/collect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// servicePorts formats the ports like kubectl, name:port/protocol with the
+// node port appended as ->nodePort.
+func servicePorts(ports []model.ServicePort) string {
+	s := make([]string, len(ports))
+	for i, p := range ports {
+		port := strconv.Itoa(int(p.Port)) + "/" + string(p.Protocol)
+		if p.Name != "" {
+			port = p.Name + ":" + port
+		}
+		if p.NodePort != 0 {
+			port += "->" + strconv.Itoa(int(p.NodePort))
+		}
+		s[i] = port
+	}
+	return strings.Join(s, ",")
+}
+
+// formatLabels formats a label map as a sorted selector, k1=v1,k2=v2.
+func formatLabels(labels map[string]string) string {
+	s := make([]string, 0, len(labels))
+	for k, v := range labels {
+		s = append(s, k+"="+v)
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+func loadBalancerIngress(status model.LoadBalancerStatus) string {
+	s := make([]string, len(status.Ingress))
+	for i, ingress := range status.Ingress {
+		s[i] = ingress.IP
+		if s[i] == "" {
+			s[i] = ingress.Hostname
+		}
+	}
+	return strings.Join(s, ",")
+}
+
+// addressPods returns the namespace/name of the pods behind addresses; an
+// address without a pod target is listed by its IP.
+func addressPods(addresses []model.EndpointAddress) []string {
+	pods := make([]string, len(addresses))
+	for i, a := range addresses {
+		if a.TargetRef != nil && a.TargetRef.Kind == "Pod" {
+			pods[i] = a.TargetRef.Namespace + "/" + a.TargetRef.Name
+		} else {
+			pods[i] = a.IP
+		}
+	}
+	return pods
+}
+
+func storeEndpoints(item interface{}, l *Listing) error {
+	v := *item.(*model.Endpoints)
+	var endpoints model.ServiceEndpoints
+	var ready, notReady, ports []string
+	for _, subset := range v.Subsets {
+		ready = append(ready, addressPods(subset.Addresses)...)
+		notReady = append(notReady, addressPods(subset.NotReadyAddresses)...)
+		for _, p := range subset.Ports {
+			port := strconv.Itoa(int(p.Port)) + "/" + string(p.Protocol)
+			if !containsString(ports, port) {
+				ports = append(ports, port)
+			}
+		}
+	}
+	endpoints.Namespace = v.Namespace
+	endpoints.Service_name = v.Name
+	endpoints.Ready_addresses = len(ready)
+	endpoints.Not_ready_addresses = len(notReady)
+	endpoints.Ready_pods = strings.Join(ready, ",")
+	endpoints.Not_ready_pods = strings.Join(notReady, ",")
+	endpoints.Ports = strings.Join(ports, ",")
+	endpoints.Record_time = l.recordTime()
+	endpoints.Tag = l.Run.Tag
+	if err := insertRow(&endpoints); err != nil {
+		return err
+	}
+	if err := insertLabels("endpoints", v.ObjectMeta, l); err != nil {
+		return err
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package collect
+
+import (
+	model "model/collect"
+	"service/collect/fakeapi"
+	"testing"
+)
+
+func TestStoreService(t *testing.T) {
+	store, l := memListing(t, "services")
+	service := fakeapi.Service("default", "web")
+	service.Spec.Type = model.ServiceTypeLoadBalancer
+	service.Spec.Selector["tier"] = "frontend"
+	service.Spec.Ports = []model.ServicePort{
+		{Name: "http", Protocol: model.ProtocolTCP, Port: 80, NodePort: 30080},
+		{Protocol: model.ProtocolUDP, Port: 53},
+	}
+	service.Spec.ExternalIPs = []string{"192.168.0.10", "192.168.0.11"}
+	service.Spec.SessionAffinity = model.ServiceAffinityClientIP
+	service.Status.LoadBalancer.Ingress = []model.LoadBalancerIngress{{IP: "35.0.0.1"}, {Hostname: "web.elb.example.com"}}
+	if err := storeService(service, l); err != nil {
+		t.Fatal(err)
+	}
+	rows := store.Rows("services")
+	if len(rows) != 1 {
+		t.Fatalf("%d services stored", len(rows))
+	}
+	s := rows[0].(*model.Services)
+	if s.Namespace != "default" || s.Service_name != "web" || s.Type != "LoadBalancer" || s.Cluster_ip != "10.254.0.1" ||
+		s.Affinity != "ClientIP" || s.External_ips != "192.168.0.10,192.168.0.11" {
+		t.Errorf("service = %+v", s)
+	}
+	if s.Ports != "http:80/TCP->30080,53/UDP" {
+		t.Errorf("ports = %q", s.Ports)
+	}
+	if s.Selector != "app=web,tier=frontend" {
+		t.Errorf("selector = %q", s.Selector)
+	}
+	if s.Lb_ingress != "35.0.0.1,web.elb.example.com" {
+		t.Errorf("load balancer ingress = %q", s.Lb_ingress)
+	}
+}
+
+func TestStoreEndpoints(t *testing.T) {
+	store, l := memListing(t, "endpoints")
+	pod := func(name string) *model.ObjectReference {
+		return &model.ObjectReference{Kind: "Pod", Namespace: "default", Name: name}
+	}
+	endpoints := model.Endpoints{
+		ObjectMeta: model.ObjectMeta{Namespace: "default", Name: "web"},
+		Subsets: []model.EndpointSubset{
+			{
+				Addresses:         []model.EndpointAddress{{IP: "10.1.0.1", TargetRef: pod("web-1")}, {IP: "10.1.0.2", TargetRef: pod("web-2")}},
+				NotReadyAddresses: []model.EndpointAddress{{IP: "10.1.0.3", TargetRef: pod("web-3")}},
+				Ports:             []model.EndpointPort{{Name: "http", Port: 8080, Protocol: model.ProtocolTCP}},
+			},
+			{
+				// an address set by hand, without a pod behind it
+				Addresses: []model.EndpointAddress{{IP: "192.168.0.20"}},
+				Ports: []model.EndpointPort{
+					{Name: "http", Port: 8080, Protocol: model.ProtocolTCP},
+					{Name: "metrics", Port: 9090, Protocol: model.ProtocolTCP},
+				},
+			},
+		},
+	}
+	if err := storeEndpoints(&endpoints, l); err != nil {
+		t.Fatal(err)
+	}
+	rows := store.Rows("service_endpoints")
+	if len(rows) != 1 {
+		t.Fatalf("%d endpoints stored", len(rows))
+	}
+	e := rows[0].(*model.ServiceEndpoints)
+	if e.Namespace != "default" || e.Service_name != "web" || e.Ready_addresses != 3 || e.Not_ready_addresses != 1 {
+		t.Errorf("endpoints = %+v", e)
+	}
+	if e.Ready_pods != "default/web-1,default/web-2,192.168.0.20" || e.Not_ready_pods != "default/web-3" {
+		t.Errorf("ready %q, not ready %q", e.Ready_pods, e.Not_ready_pods)
+	}
+	if e.Ports != "8080/TCP,9090/TCP" {
+		t.Errorf("ports = %q", e.Ports)
+	}
+
+	// a service whose pods are all gone still gets its row
+	if err := storeEndpoints(&model.Endpoints{ObjectMeta: model.ObjectMeta{Namespace: "default", Name: "idle"}}, l); err != nil {
+		t.Fatal(err)
+	}
+	if e := store.Rows("service_endpoints")[1].(*model.ServiceEndpoints); e.Service_name != "idle" || e.Ready_addresses != 0 || e.Ready_pods != "" {
+		t.Errorf("endpoints = %+v", e)
+	}
+}
@@ -0,0 +1,42 @@
+package collect
+
+import (
+	model "model/collect"
+	"strings"
+)
+
+// Sharder decides what this replica collects when several split the
+// cluster.
+type Sharder interface {
+	OwnsNamespace(namespace string) bool
+	OwnsClusterScoped() bool
+	Describe() string
+}
+
+// CurrentShard is nil when this replica collects the whole cluster.
+var CurrentShard Sharder
+
+// shardNamespaces lists the namespaces of the cluster this replica owns, and
+// all of them.
+func shardNamespaces() ([]string, []string, error) {
+	var list model.NamespaceList
+	if err := GainResourceFromK8s(&list, "/api/v1/namespaces"); err != nil {
+		return nil, nil, err
+	}
+	owned := []string{}
+	all := []string{}
+	for _, ns := range list.Items {
+		all = append(all, ns.Name)
+		if CurrentShard.OwnsNamespace(ns.Name) {
+			owned = append(owned, ns.Name)
+		}
+	}
+	return owned, all, nil
+}
+
+// namespacedPath turns the list path of a resource into the one of its
+// objects in namespace: /api/v1/pods becomes /api/v1/namespaces/ns/pods.
+func namespacedPath(path string, namespace string) string {
+	i := strings.LastIndex(path, "/")
+	return path[:i] + "/namespaces/" + namespace + path[i:]
+}
@@ -0,0 +1,109 @@
+package collect
+
+import (
+	model "model/collect"
+	"strings"
+)
+
+// Before storage classes got a field of their own they were set with these
+// annotations.
+var storageClassAnnotations = []string{
+	"volume.beta.kubernetes.io/storage-class",
+	"volume.alpha.kubernetes.io/storage-class",
+}
+
+// storageClass returns the storage class set by field, or by annotation on
+// clusters that predate the field.
+func storageClass(field string, meta model.ObjectMeta) string {
+	if field != "" {
+		return field
+	}
+	for _, key := range storageClassAnnotations {
+		if class, ok := meta.Annotations[key]; ok {
+			return class
+		}
+	}
+	return ""
+}
+
+func accessModes(modes []model.PersistentVolumeAccessMode) string {
+	s := make([]string, len(modes))
+	for i, mode := range modes {
+		s[i] = string(mode)
+	}
+	return strings.Join(s, ",")
+}
+
+func storePersistentVolume(item interface{}, l *Listing) error {
+	v := *item.(*model.PersistentVolume)
+	var volume model.PersistentVolumes
+	volume.Volume_name = v.Name
+	volume.Capacity = quantityString(v.Spec.Capacity, model.ResourceStorage)
+	volume.Access_modes = accessModes(v.Spec.AccessModes)
+	volume.Reclaim_policy = string(v.Spec.PersistentVolumeReclaimPolicy)
+	volume.Storage_class = storageClass(v.Spec.StorageClassName, v.ObjectMeta)
+	volume.Phase = string(v.Status.Phase)
+	volume.Reason = v.Status.Reason
+	if v.Spec.ClaimRef != nil {
+		volume.Claim_namespace = v.Spec.ClaimRef.Namespace
+		volume.Claim_name = v.Spec.ClaimRef.Name
+	}
+	volume.Create_time = v.CreationTimestamp.Format("2006-01-02 15:04:05")
+	volume.Record_time = l.recordTime()
+	volume.Tag = l.Run.Tag
+	if err := insertRow(&volume); err != nil {
+		return err
+	}
+	if err := insertLabels("persistentvolumes", v.ObjectMeta, l); err != nil {
+		return err
+	}
+	return nil
+}
+
+func storePersistentVolumeClaim(item interface{}, l *Listing) error {
+	v := *item.(*model.PersistentVolumeClaim)
+	var claim model.PersistentVolumeClaims
+	claim.Namespace = v.Namespace
+	claim.Claim_name = v.Name
+	claim.Phase = string(v.Status.Phase)
+	claim.Volume_name = v.Spec.VolumeName
+	claim.Requested = quantityString(v.Spec.Resources.Requests, model.ResourceStorage)
+	claim.Capacity = quantityString(v.Status.Capacity, model.ResourceStorage)
+	claim.Access_modes = accessModes(v.Spec.AccessModes)
+	if v.Spec.StorageClassName != nil {
+		claim.Storage_class = *v.Spec.StorageClassName
+	} else {
+		claim.Storage_class = storageClass("", v.ObjectMeta)
+	}
+	claim.Create_time = v.CreationTimestamp.Format("2006-01-02 15:04:05")
+	claim.Record_time = l.recordTime()
+	claim.Tag = l.Run.Tag
+	if err := insertRow(&claim); err != nil {
+		return err
+	}
+	if err := insertLabels("persistentvolumeclaims", v.ObjectMeta, l); err != nil {
+		return err
+	}
+	return nil
+}
+
+// insertPodVolumeClaims records the claims mounted by pod.
+func insertPodVolumeClaims(pod model.Pod, l *Listing) error {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		var mount model.PodVolumeClaims
+		mount.Namespace = pod.Namespace
+		mount.Pod_name = pod.Name
+		mount.Volume = volume.Name
+		mount.Claim_name = volume.PersistentVolumeClaim.ClaimName
+		mount.Read_only = volume.PersistentVolumeClaim.ReadOnly
+		mount.Record_time = l.recordTime()
+		mount.Tag = l.Run.Tag
+		if err := insertRow(&mount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package collect
+
+import (
+	model "model/collect"
+	"testing"
+
+	"k8s.io/client-go/pkg/api/resource"
+)
+
+func TestStorePersistentVolume(t *testing.T) {
+	store, l := memListing(t, "persistentvolumes")
+	volume := model.PersistentVolume{
+		ObjectMeta: model.ObjectMeta{
+			Name:        "pv-1",
+			Annotations: map[string]string{"volume.beta.kubernetes.io/storage-class": "slow"},
+		},
+		Spec: model.PersistentVolumeSpec{
+			Capacity:                      model.ResourceList{model.ResourceStorage: resource.MustParse("10Gi")},
+			AccessModes:                   []model.PersistentVolumeAccessMode{model.ReadWriteOnce, model.ReadOnlyMany},
+			ClaimRef:                      &model.ObjectReference{Namespace: "default", Name: "data"},
+			PersistentVolumeReclaimPolicy: model.PersistentVolumeReclaimRetain,
+		},
+		Status: model.PersistentVolumeStatus{Phase: model.VolumeBound},
+	}
+	if err := storePersistentVolume(&volume, l); err != nil {
+		t.Fatal(err)
+	}
+	rows := store.Rows("persistent_volumes")
+	if len(rows) != 1 {
+		t.Fatalf("%d volumes stored", len(rows))
+	}
+	if v := rows[0].(*model.PersistentVolumes); v.Capacity != "10Gi" || v.Access_modes != "ReadWriteOnce,ReadOnlyMany" ||
+		v.Reclaim_policy != "Retain" || v.Storage_class != "slow" || v.Phase != "Bound" ||
+		v.Claim_namespace != "default" || v.Claim_name != "data" {
+		t.Errorf("volume = %+v", v)
+	}
+
+	// the field wins over the annotation of older clusters
+	volume.Spec.StorageClassName = "fast"
+	volume.Spec.ClaimRef = nil
+	if err := storePersistentVolume(&volume, l); err != nil {
+		t.Fatal(err)
+	}
+	if v := store.Rows("persistent_volumes")[1].(*model.PersistentVolumes); v.Storage_class != "fast" || v.Claim_name != "" {
+		t.Errorf("volume = %+v", v)
+	}
+}
+
+func TestStorePersistentVolumeClaim(t *testing.T) {
+	store, l := memListing(t, "persistentvolumeclaims")
+	claim := model.PersistentVolumeClaim{
+		ObjectMeta: model.ObjectMeta{
+			Namespace:   "default",
+			Name:        "data",
+			Annotations: map[string]string{"volume.alpha.kubernetes.io/storage-class": "slow"},
+		},
+		Spec: model.PersistentVolumeClaimSpec{
+			AccessModes: []model.PersistentVolumeAccessMode{model.ReadWriteOnce},
+			Resources: model.ResourceRequirements{
+				Requests: model.ResourceList{model.ResourceStorage: resource.MustParse("5Gi")},
+			},
+		},
+		Status: model.PersistentVolumeClaimStatus{Phase: model.ClaimPending},
+	}
+	if err := storePersistentVolumeClaim(&claim, l); err != nil {
+		t.Fatal(err)
+	}
+	// an empty class set in the field disables dynamic provisioning, and
+	// is not overridden by the annotation
+	empty := ""
+	claim.Spec.StorageClassName = &empty
+	if err := storePersistentVolumeClaim(&claim, l); err != nil {
+		t.Fatal(err)
+	}
+	rows := store.Rows("persistent_volume_claims")
+	if len(rows) != 2 {
+		t.Fatalf("%d claims stored", len(rows))
+	}
+	if c := rows[0].(*model.PersistentVolumeClaims); c.Namespace != "default" || c.Claim_name != "data" ||
+		c.Phase != "Pending" || c.Requested != "5Gi" || c.Capacity != "" || c.Access_modes != "ReadWriteOnce" ||
+		c.Storage_class != "slow" {
+		t.Errorf("claim = %+v", c)
+	}
+	if c := rows[1].(*model.PersistentVolumeClaims); c.Storage_class != "" {
+		t.Errorf("claim = %+v", c)
+	}
+}
+
+func TestInsertPodVolumeClaims(t *testing.T) {
+	store, l := memListing(t, "pods")
+	pod := model.Pod{
+		ObjectMeta: model.ObjectMeta{Namespace: "default", Name: "db-0"},
+		Spec: model.PodSpec{Volumes: []model.Volume{
+			{Name: "data", VolumeSource: model.VolumeSource{
+				PersistentVolumeClaim: &model.PersistentVolumeClaimVolumeSource{ClaimName: "data-db-0"},
+			}},
+			{Name: "scratch", VolumeSource: model.VolumeSource{EmptyDir: &model.EmptyDirVolumeSource{}}},
+			{Name: "seed", VolumeSource: model.VolumeSource{
+				PersistentVolumeClaim: &model.PersistentVolumeClaimVolumeSource{ClaimName: "seed", ReadOnly: true},
+			}},
+		}},
+	}
+	if err := insertPodVolumeClaims(pod, l); err != nil {
+		t.Fatal(err)
+	}
+	rows := store.Rows("pod_volume_claims")
+	if len(rows) != 2 {
+		t.Fatalf("%d mounts stored", len(rows))
+	}
+	if m := rows[0].(*model.PodVolumeClaims); m.Pod_name != "db-0" || m.Volume != "data" || m.Claim_name != "data-db-0" || m.Read_only {
+		t.Errorf("mount = %+v", m)
+	}
+	if m := rows[1].(*model.PodVolumeClaims); m.Volume != "seed" || !m.Read_only {
+		t.Errorf("mount = %+v", m)
+	}
+}
@@ -0,0 +1,176 @@
+package collect
+
+import (
+	"common"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// UseProtobuf makes list requests ask for protobuf, which the apiserver
+// answers for built-in kinds from 1.5 on; anything else is answered in JSON.
+var UseProtobuf = false
+
+// listStats counts what a listing read from the apiserver.
+type listStats struct {
+	Bytes    int64
+	Encoding string
+}
+
+// ListPageSize is the number of items asked for per list request. Servers
+// before 1.9 ignore the limit and return the whole list, which is then still
+// decoded one item at a time.
+var ListPageSize = 500
+
+// streamList lists path page by page, following the continue token, and hands
+// every item to each as soon as it is decoded. An expired continue token
+// fails the listing, the pages read so far are kept.
+func streamList(path string, protobuf bool, newItem func() interface{}, each func(item interface{}) error, stats *listStats) error {
+	token := ""
+	for {
+		next, err := streamPage(pagePath(path, token), protobuf, newItem, each, stats)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+func pagePath(path string, token string) string {
+	if ListPageSize <= 0 {
+		return path
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	p := path + sep + "limit=" + strconv.Itoa(ListPageSize)
+	if token != "" {
+		p += "&continue=" + url.QueryEscape(token)
+	}
+	return p
+}
+
+func streamPage(urls string, protobuf bool, newItem func() interface{}, each func(item interface{}) error, stats *listStats) (string, error) {
+	header := make(http.Header)
+	if protobuf {
+		header.Set("Accept", protobufContentType+", application/json")
+	}
+	resp, err := kubeSource.get(urls, header)
+	if err != nil {
+		common.LogErr(err)
+		return "", err
+	}
+	progress()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("get %s: %s", urls, resp.Status)
+		common.LogErr(err)
+		return "", err
+	}
+	body := &countingReader{r: resp.Body}
+	defer func() { stats.Bytes += body.n }()
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), protobufContentType) {
+		stats.Encoding = "protobuf"
+		// protobuf lists are not streamed, a page is held in memory
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+		return decodeProtobufList(data, newItem, each)
+	}
+	stats.Encoding = "json"
+	return decodeList(body, newItem, each, urls)
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// decodeList reads a list object token by token so only one item is held in
+// memory at a time, and returns the continue token of the page. Like
+// decodeTolerant it skips fields whose type changed between releases.
+func decodeList(r io.Reader, newItem func() interface{}, each func(item interface{}) error, urls string) (string, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return "", err
+	}
+	var meta struct {
+		Continue string `json:"continue"`
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t {
+		case "items":
+			if err := decodeItems(dec, newItem, each, urls); err != nil {
+				return "", err
+			}
+		case "metadata":
+			if err := dec.Decode(&meta); err != nil {
+				return "", err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return "", err
+			}
+		}
+	}
+	return meta.Continue, expectDelim(dec, '}')
+}
+
+func decodeItems(dec *json.Decoder, newItem func() interface{}, each func(item interface{}) error, urls string) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return nil
+	}
+	if d, ok := t.(json.Delim); !ok || d != '[' {
+		return fmt.Errorf("get %s: items is not an array", urls)
+	}
+	for dec.More() {
+		item := newItem()
+		if err := dec.Decode(item); err != nil {
+			typeErr, ok := err.(*json.UnmarshalTypeError)
+			if !ok {
+				return err
+			}
+			common.DebugPrint("get", urls, "skipped a field of an unexpected type:", typeErr)
+		}
+		if err := each(item); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %v in list, got %v", want, t)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package collect
+
+import (
+	model "model/collect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeList(t *testing.T) {
+	body := `{"kind": "PodList", "apiVersion": "v1",
+		"metadata": {"resourceVersion": "10", "continue": "next-page"},
+		"items": [
+			{"metadata": {"name": "web-1", "extra": {"a": [1, 2]}}, "spec": {"nodeName": "n1"}},
+			{"metadata": {"name": "web-2"}, "spec": {"nodeName": 7}},
+			{"metadata": {"name": "web-3"}, "status": {"phase": "Running"}}
+		]}`
+	var names []string
+	token, err := decodeList(strings.NewReader(body), func() interface{} { return new(model.Pod) }, func(item interface{}) error {
+		names = append(names, item.(*model.Pod).Name)
+		return nil
+	}, "/api/v1/pods")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "next-page" {
+		t.Errorf("continue = %q", token)
+	}
+	if strings.Join(names, ",") != "web-1,web-2,web-3" {
+		t.Errorf("items = %v", names)
+	}
+
+	_, err = decodeList(strings.NewReader(`{"items": null}`), func() interface{} { return new(model.Pod) }, func(interface{}) error {
+		t.Error("no item expected")
+		return nil
+	}, "")
+	if err != nil {
+		t.Error(err)
+	}
+	if _, err := decodeList(strings.NewReader(`{"items": [{"metadata": {}`), func() interface{} { return new(model.Pod) }, func(interface{}) error { return nil }, ""); err == nil {
+		t.Error("truncated list should fail")
+	}
+}
+
+func TestPagePath(t *testing.T) {
+	if p := pagePath("/api/v1/pods", ""); p != "/api/v1/pods?limit=500" {
+		t.Errorf("first page = %s", p)
+	}
+	if p := pagePath("/api/v1/pods", "a b"); p != "/api/v1/pods?limit=500&continue=a+b" {
+		t.Errorf("next page = %s", p)
+	}
+}
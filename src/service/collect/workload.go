@@ -0,0 +1,197 @@
+package collect
+
+import (
+	model "model/collect"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/pkg/api/unversioned"
+)
+
+// newWorkload fills the columns every workload kind shares.
+func newWorkload(kind string, meta model.ObjectMeta, l *Listing) model.Workloads {
+	var w model.Workloads
+	w.Kind = kind
+	w.Namespace = meta.Namespace
+	w.Workload_name = meta.Name
+	w.Uid = string(meta.UID)
+	w.Generation = meta.Generation
+	if ref := controllerRef(meta); ref != nil {
+		w.Owner_kind = ref.Kind
+		w.Owner_name = ref.Name
+		w.Owner_uid = string(ref.UID)
+	}
+	w.Create_time = objectTime(meta.CreationTimestamp.Time)
+	w.Record_time = l.recordTime()
+	w.Tag = l.Run.Tag
+	return w
+}
+
+func insertWorkload(w *model.Workloads, kind string, meta model.ObjectMeta, l *Listing) error {
+	if err := insertRow(w); err != nil {
+		return err
+	}
+	return insertLabels(kind, meta, l)
+}
+
+// converged reports whether a rollout is done: the controller saw the latest
+// spec and every count reached the desired one.
+func converged(w model.Workloads) bool {
+	if w.Observed_generation < w.Generation {
+		return false
+	}
+	return w.Current == w.Desired && w.Updated >= w.Desired && w.Ready >= w.Desired && w.Available >= w.Desired
+}
+
+func desiredReplicas(replicas *int32) int {
+	if replicas == nil {
+		return 1
+	}
+	return int(*replicas)
+}
+
+// labelSelector formats a selector like the service selectors, match
+// expressions follow in selector syntax.
+func labelSelector(s *unversioned.LabelSelector) string {
+	if s == nil {
+		return ""
+	}
+	terms := []string{}
+	if len(s.MatchLabels) > 0 {
+		terms = append(terms, formatLabels(s.MatchLabels))
+	}
+	for _, e := range s.MatchExpressions {
+		switch e.Operator {
+		case unversioned.LabelSelectorOpIn, unversioned.LabelSelectorOpNotIn:
+			values := append([]string(nil), e.Values...)
+			sort.Strings(values)
+			terms = append(terms, e.Key+" "+strings.ToLower(string(e.Operator))+" ("+strings.Join(values, ",")+")")
+		case unversioned.LabelSelectorOpExists:
+			terms = append(terms, e.Key)
+		case unversioned.LabelSelectorOpDoesNotExist:
+			terms = append(terms, "!"+e.Key)
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+func replicaFailure(conditions []model.ReplicationControllerCondition) string {
+	for _, c := range conditions {
+		if c.Type == model.ReplicationControllerReplicaFailure && c.Status == model.ConditionTrue {
+			return c.Reason
+		}
+	}
+	return ""
+}
+
+func storeReplicationController(item interface{}, l *Listing) error {
+	v := *item.(*model.ReplicationController)
+	w := newWorkload("ReplicationController", v.ObjectMeta, l)
+	w.Desired = desiredReplicas(v.Spec.Replicas)
+	w.Current = int(v.Status.Replicas)
+	w.Updated = int(v.Status.FullyLabeledReplicas)
+	w.Ready = int(v.Status.ReadyReplicas)
+	w.Available = int(v.Status.AvailableReplicas)
+	w.Observed_generation = v.Status.ObservedGeneration
+	w.Selector = formatLabels(v.Spec.Selector)
+	w.Reason = replicaFailure(v.Status.Conditions)
+	w.Converged = converged(w)
+	return insertWorkload(&w, "replicationcontrollers", v.ObjectMeta, l)
+}
+
+func storeReplicaSet(item interface{}, l *Listing) error {
+	v := *item.(*model.ReplicaSet)
+	w := newWorkload("ReplicaSet", v.ObjectMeta, l)
+	w.Desired = desiredReplicas(v.Spec.Replicas)
+	w.Current = int(v.Status.Replicas)
+	w.Updated = int(v.Status.FullyLabeledReplicas)
+	w.Ready = int(v.Status.ReadyReplicas)
+	w.Available = int(v.Status.AvailableReplicas)
+	w.Observed_generation = v.Status.ObservedGeneration
+	w.Selector = labelSelector(v.Spec.Selector)
+	w.Reason = replicaFailure(v.Status.Conditions)
+	w.Converged = converged(w)
+	return insertWorkload(&w, "replicasets", v.ObjectMeta, l)
+}
+
+func storeDeployment(item interface{}, l *Listing) error {
+	v := *item.(*model.Deployment)
+	w := newWorkload("Deployment", v.ObjectMeta, l)
+	w.Desired = desiredReplicas(v.Spec.Replicas)
+	w.Current = int(v.Status.Replicas)
+	w.Updated = int(v.Status.UpdatedReplicas)
+	w.Ready = int(v.Status.ReadyReplicas)
+	w.Available = int(v.Status.AvailableReplicas)
+	w.Observed_generation = v.Status.ObservedGeneration
+	w.Selector = labelSelector(v.Spec.Selector)
+	w.Paused = v.Spec.Paused
+	for _, c := range v.Status.Conditions {
+		if c.Status == model.ConditionFalse && c.Type == model.DeploymentProgressing ||
+			c.Status == model.ConditionTrue && c.Type == model.DeploymentReplicaFailure {
+			w.Reason = c.Reason
+		}
+	}
+	w.Converged = converged(w)
+	return insertWorkload(&w, "deployments", v.ObjectMeta, l)
+}
+
+func storeDaemonSet(item interface{}, l *Listing) error {
+	v := *item.(*model.DaemonSet)
+	w := newWorkload("DaemonSet", v.ObjectMeta, l)
+	w.Desired = int(v.Status.DesiredNumberScheduled)
+	w.Current = int(v.Status.CurrentNumberScheduled)
+	w.Updated = int(v.Status.UpdatedNumberScheduled)
+	w.Ready = int(v.Status.NumberReady)
+	w.Available = int(v.Status.NumberAvailable)
+	// clusters before 1.6 report neither updated nor available nodes
+	if w.Updated == 0 && w.Available == 0 {
+		w.Updated, w.Available = w.Current, w.Ready
+	}
+	w.Observed_generation = v.Status.ObservedGeneration
+	w.Selector = labelSelector(v.Spec.Selector)
+	w.Converged = converged(w)
+	return insertWorkload(&w, "daemonsets", v.ObjectMeta, l)
+}
+
+func storeStatefulSet(item interface{}, l *Listing) error {
+	v := *item.(*model.StatefulSet)
+	w := newWorkload("StatefulSet", v.ObjectMeta, l)
+	w.Desired = desiredReplicas(v.Spec.Replicas)
+	w.Current = int(v.Status.Replicas)
+	w.Ready = int(v.Status.ReadyReplicas)
+	w.Updated = int(v.Status.UpdatedReplicas)
+	// StatefulSets report no available replicas, and no updated ones
+	// before 1.7
+	if w.Updated == 0 {
+		w.Updated = w.Current
+	}
+	w.Available = w.Ready
+	if v.Status.ObservedGeneration != nil {
+		w.Observed_generation = *v.Status.ObservedGeneration
+	}
+	w.Selector = labelSelector(v.Spec.Selector)
+	w.Converged = converged(w)
+	return insertWorkload(&w, "statefulsets", v.ObjectMeta, l)
+}
+
+func storeJob(item interface{}, l *Listing) error {
+	v := *item.(*model.Job)
+	w := newWorkload("Job", v.ObjectMeta, l)
+	w.Desired = desiredReplicas(v.Spec.Completions)
+	w.Current = int(v.Status.Active)
+	w.Ready = int(v.Status.Succeeded)
+	w.Failed = int(v.Status.Failed)
+	w.Selector = labelSelector(v.Spec.Selector)
+	for _, c := range v.Status.Conditions {
+		if c.Status != model.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case model.JobComplete:
+			w.Converged = true
+		case model.JobFailed:
+			w.Reason = c.Reason
+		}
+	}
+	return insertWorkload(&w, "jobs", v.ObjectMeta, l)
+}
@@ -0,0 +1,203 @@
+package election
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LeaderAnnotation is where the Kubernetes client libraries keep their
+// leader record, so kubectl shows our leader the usual way.
+const LeaderAnnotation = "control-plane.alpha.kubernetes.io/leader"
+
+type leaderRecord struct {
+	HolderIdentity       string `json:"holderIdentity"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds"`
+	AcquireTime          string `json:"acquireTime"`
+	RenewTime            string `json:"renewTime"`
+	LeaderTransitions    int    `json:"leaderTransitions"`
+}
+
+// AnnotationLock keeps the leader record in an annotation of an Endpoints or
+// ConfigMap object. Updates carry the resourceVersion read, so of two
+// replicas updating at once one gets a conflict. A record is only taken over
+// once it has not changed for its lease duration as measured here, clocks of
+// the replicas need not agree.
+type AnnotationLock struct {
+	Master        string
+	Resource      string
+	Namespace     string
+	Name          string
+	LeaseDuration time.Duration
+	Client        *http.Client
+
+	lock         sync.Mutex
+	observed     string
+	observedTime time.Time
+}
+
+// NewAnnotationLock locks resource, "endpoints" or "configmaps", named
+// namespace/name on the apiserver at master.
+func NewAnnotationLock(master string, resource string, namespace string, name string) *AnnotationLock {
+	return &AnnotationLock{
+		Master:        master,
+		Resource:      resource,
+		Namespace:     namespace,
+		Name:          name,
+		LeaseDuration: 15 * time.Second,
+		Client:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (l *AnnotationLock) collectionPath() string {
+	return l.Master + "/api/v1/namespaces/" + l.Namespace + "/" + l.Resource
+}
+
+func (l *AnnotationLock) objectPath() string {
+	return l.collectionPath() + "/" + l.Name
+}
+
+// get returns the lock object, nil when it does not exist yet.
+func (l *AnnotationLock) get() (map[string]interface{}, error) {
+	resp, err := l.Client.Get(l.objectPath())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("election: get %s: %s", l.objectPath(), resp.Status)
+	}
+	var obj map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// send writes obj and returns the status code; a conflict is not an error.
+func (l *AnnotationLock) send(method string, url string, obj map[string]interface{}) (int, error) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusConflict:
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, fmt.Errorf("election: %s %s: %s", method, url, resp.Status)
+}
+
+func (l *AnnotationLock) record(obj map[string]interface{}) (map[string]interface{}, leaderRecord, string) {
+	meta, _ := obj["metadata"].(map[string]interface{})
+	if meta == nil {
+		meta = make(map[string]interface{})
+		obj["metadata"] = meta
+	}
+	annotations, _ := meta["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = make(map[string]interface{})
+		meta["annotations"] = annotations
+	}
+	raw, _ := annotations[LeaderAnnotation].(string)
+	var rec leaderRecord
+	if raw != "" {
+		json.Unmarshal([]byte(raw), &rec)
+	}
+	return annotations, rec, raw
+}
+
+func (l *AnnotationLock) TryAcquire(identity string) (bool, string, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	obj, err := l.get()
+	if err != nil {
+		return false, "", err
+	}
+	now := time.Now()
+	lease := int(l.LeaseDuration / time.Second)
+	mine := leaderRecord{HolderIdentity: identity, LeaseDurationSeconds: lease,
+		AcquireTime: now.UTC().Format(time.RFC3339), RenewTime: now.UTC().Format(time.RFC3339)}
+	if obj == nil {
+		obj = map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       map[string]string{"endpoints": "Endpoints", "configmaps": "ConfigMap"}[l.Resource],
+			"metadata":   map[string]interface{}{"name": l.Name, "namespace": l.Namespace},
+		}
+		annotations, _, _ := l.record(obj)
+		raw, _ := json.Marshal(mine)
+		annotations[LeaderAnnotation] = string(raw)
+		code, err := l.send("POST", l.collectionPath(), obj)
+		return code == http.StatusCreated, "", err
+	}
+
+	annotations, old, raw := l.record(obj)
+	if raw != l.observed {
+		l.observed = raw
+		l.observedTime = now
+	}
+	if old.HolderIdentity != "" && old.HolderIdentity != identity {
+		expires := l.observedTime.Add(time.Duration(old.LeaseDurationSeconds) * time.Second)
+		if now.Before(expires) {
+			return false, old.HolderIdentity, nil
+		}
+	}
+	if old.HolderIdentity == identity {
+		mine.AcquireTime = old.AcquireTime
+		mine.LeaderTransitions = old.LeaderTransitions
+	} else if raw != "" {
+		mine.LeaderTransitions = old.LeaderTransitions + 1
+	}
+	rec, _ := json.Marshal(mine)
+	annotations[LeaderAnnotation] = string(rec)
+	code, err := l.send("PUT", l.objectPath(), obj)
+	if err != nil || code == http.StatusConflict {
+		return false, old.HolderIdentity, err
+	}
+	l.observed = string(rec)
+	l.observedTime = now
+	return true, identity, nil
+}
+
+// Release clears the holder, with a lease of a second, so another replica
+// takes over at once.
+func (l *AnnotationLock) Release(identity string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	obj, err := l.get()
+	if err != nil || obj == nil {
+		return err
+	}
+	annotations, old, _ := l.record(obj)
+	if old.HolderIdentity != identity {
+		return nil
+	}
+	old.HolderIdentity = ""
+	old.LeaseDurationSeconds = 1
+	old.RenewTime = time.Now().UTC().Format(time.RFC3339)
+	rec, _ := json.Marshal(old)
+	annotations[LeaderAnnotation] = string(rec)
+	_, err = l.send("PUT", l.objectPath(), obj)
+	return err
+}
+
+func (l *AnnotationLock) Describe() string {
+	return l.Resource + " " + l.Namespace + "/" + l.Name + " annotation " + LeaderAnnotation
+}
@@ -0,0 +1,174 @@
+// Package election picks one leader among the collector replicas. Only the
+// leader collects; the others serve the read APIs.
+package election
+
+import (
+	"common"
+	"sync"
+	"time"
+)
+
+// Lock is the shared resource the replicas compete for.
+type Lock interface {
+	// TryAcquire takes the lock for identity, or renews it when identity
+	// holds it already, and reports whether identity holds it now and who
+	// does when known.
+	TryAcquire(identity string) (held bool, holder string, err error)
+	// Release gives the lock up if identity holds it.
+	Release(identity string) error
+	Describe() string
+}
+
+// Transition is a change of leadership seen by this replica.
+type Transition struct {
+	Time   string `json:"time"`
+	Leader bool   `json:"leader"`
+	Reason string `json:"reason"`
+}
+
+// Status is what the status endpoint shows.
+type Status struct {
+	Enabled      bool         `json:"enabled"`
+	Lock         string       `json:"lock,omitempty"`
+	Identity     string       `json:"identity,omitempty"`
+	Leader       bool         `json:"leader"`
+	Holder       string       `json:"holder,omitempty"`
+	Leader_since string       `json:"leader_since,omitempty"`
+	Last_renew   string       `json:"last_renew,omitempty"`
+	Transitions  int          `json:"transitions"`
+	Recent       []Transition `json:"recent"`
+	Error        string       `json:"error,omitempty"`
+}
+
+// keptTransitions is how many transitions Status lists.
+const keptTransitions = 10
+
+// Elector keeps trying to take or renew Lock. A leader that could not renew
+// for RenewDeadline steps down, before the lease it took runs out for the
+// others. OnStepDown, when set, is called each time this replica stops
+// leading, so work begun as the leader can be stopped.
+type Elector struct {
+	Lock          Lock
+	Identity      string
+	RetryPeriod   time.Duration
+	RenewDeadline time.Duration
+	OnStepDown    func()
+
+	lock        sync.Mutex
+	leader      bool
+	holder      string
+	since       time.Time
+	lastRenew   time.Time
+	transitions int
+	recent      []Transition
+	err         error
+}
+
+func NewElector(lock Lock, identity string) *Elector {
+	return &Elector{Lock: lock, Identity: identity, RetryPeriod: 2 * time.Second, RenewDeadline: 10 * time.Second}
+}
+
+// Run tries the lock every RetryPeriod until stop is closed, then releases
+// it.
+func (e *Elector) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(e.RetryPeriod)
+	defer ticker.Stop()
+	for {
+		e.try(time.Now())
+		select {
+		case <-stop:
+			e.step("stopped", false)
+			common.LogErr(e.Lock.Release(e.Identity))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) try(now time.Time) {
+	held, holder, err := e.Lock.TryAcquire(e.Identity)
+	e.lock.Lock()
+	e.err = err
+	if err == nil {
+		e.holder = holder
+	}
+	leader := e.leader
+	lastRenew := e.lastRenew
+	if held {
+		e.lastRenew = now
+	}
+	e.lock.Unlock()
+
+	switch {
+	case err != nil:
+		common.LogErr(err)
+		if leader && now.Sub(lastRenew) > e.RenewDeadline {
+			e.step("could not renew: "+err.Error(), false)
+		}
+	case held && !leader:
+		e.step("acquired", true)
+	case !held && leader:
+		e.step("lost to "+holder, false)
+	}
+}
+
+func (e *Elector) step(reason string, leader bool) {
+	if e.setLeader(reason, leader) && !leader && e.OnStepDown != nil {
+		e.OnStepDown()
+	}
+}
+
+// setLeader records a transition and reports whether leadership changed.
+func (e *Elector) setLeader(reason string, leader bool) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.leader == leader {
+		return false
+	}
+	now := time.Now()
+	e.leader = leader
+	if leader {
+		e.since = now
+		e.holder = e.Identity
+	} else {
+		e.since = time.Time{}
+	}
+	e.transitions++
+	e.recent = append(e.recent, Transition{Time: common.RecordTime(now), Leader: leader, Reason: reason})
+	if len(e.recent) > keptTransitions {
+		e.recent = e.recent[len(e.recent)-keptTransitions:]
+	}
+	common.DebugPrint("election:", e.Identity, "leader", leader, reason)
+	return true
+}
+
+// Leader reports whether this replica leads.
+func (e *Elector) Leader() bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.leader
+}
+
+func (e *Elector) Status() Status {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	s := Status{
+		Enabled:     true,
+		Lock:        e.Lock.Describe(),
+		Identity:    e.Identity,
+		Leader:      e.leader,
+		Holder:      e.holder,
+		Transitions: e.transitions,
+		Recent:      append([]Transition{}, e.recent...),
+	}
+	if !e.since.IsZero() {
+		s.Leader_since = common.RecordTime(e.since)
+	}
+	if !e.lastRenew.IsZero() {
+		s.Last_renew = common.RecordTime(e.lastRenew)
+	}
+	if e.err != nil {
+		s.Error = e.err.Error()
+	}
+	return s
+}
@@ -0,0 +1,151 @@
+package election
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memLock is held by whoever took it first until released.
+type memLock struct {
+	holder string
+	err    error
+}
+
+func (l *memLock) TryAcquire(identity string) (bool, string, error) {
+	if l.err != nil {
+		return false, "", l.err
+	}
+	if l.holder == "" {
+		l.holder = identity
+	}
+	return l.holder == identity, l.holder, nil
+}
+
+func (l *memLock) Release(identity string) error {
+	if l.holder == identity {
+		l.holder = ""
+	}
+	return nil
+}
+
+func (l *memLock) Describe() string { return "memory" }
+
+func TestElector(t *testing.T) {
+	lock := &memLock{}
+	a, b := NewElector(lock, "a"), NewElector(lock, "b")
+	steppedDown := 0
+	a.OnStepDown = func() { steppedDown++ }
+	now := time.Now()
+	a.try(now)
+	b.try(now)
+	if !a.Leader() || b.Leader() || b.Status().Holder != "a" {
+		t.Fatalf("a %v, b %v", a.Status(), b.Status())
+	}
+
+	// a keeps leading through errors shorter than the renew deadline
+	lock.err = errors.New("lock unreachable")
+	a.try(now.Add(a.RenewDeadline / 2))
+	if !a.Leader() || a.Status().Error == "" {
+		t.Errorf("a stepped down early: %+v", a.Status())
+	}
+	a.try(now.Add(a.RenewDeadline + time.Second))
+	if a.Leader() || steppedDown != 1 {
+		t.Errorf("a still leads past the renew deadline, stepped down %d times", steppedDown)
+	}
+
+	lock.err = nil
+	lock.Release("a")
+	b.try(now.Add(time.Minute))
+	a.try(now.Add(time.Minute))
+	s := a.Status()
+	if !b.Leader() || a.Leader() || s.Holder != "b" || s.Transitions != 2 || len(s.Recent) != 2 {
+		t.Errorf("a %+v, b %+v", s, b.Status())
+	}
+}
+
+// fakeObjects is an apiserver keeping objects with optimistic concurrency.
+type fakeObjects struct {
+	lock    sync.Mutex
+	objects map[string]map[string]interface{}
+	version int
+}
+
+func (f *fakeObjects) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	switch r.Method {
+	case "GET":
+		obj := f.objects[r.URL.Path]
+		if obj == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(obj)
+	case "POST", "PUT":
+		var obj map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&obj)
+		meta := obj["metadata"].(map[string]interface{})
+		path := r.URL.Path
+		if r.Method == "POST" {
+			path += "/" + meta["name"].(string)
+		}
+		old := f.objects[path]
+		switch {
+		case r.Method == "POST" && old != nil:
+			w.WriteHeader(http.StatusConflict)
+			return
+		case r.Method == "PUT" && (old == nil || old["metadata"].(map[string]interface{})["resourceVersion"] != meta["resourceVersion"]):
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.version++
+		meta["resourceVersion"] = strconv.Itoa(f.version)
+		f.objects[path] = obj
+		if r.Method == "POST" {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(obj)
+	}
+}
+
+func TestAnnotationLock(t *testing.T) {
+	srv := httptest.NewServer(&fakeObjects{objects: make(map[string]map[string]interface{})})
+	defer srv.Close()
+	a := NewAnnotationLock(srv.URL, "endpoints", "default", "collector")
+	b := NewAnnotationLock(srv.URL, "endpoints", "default", "collector")
+
+	if held, _, err := a.TryAcquire("a"); !held || err != nil {
+		t.Fatalf("a could not create the lock: %v", err)
+	}
+	if held, holder, err := b.TryAcquire("b"); held || holder != "a" || err != nil {
+		t.Fatalf("b took a held lock: %v %q %v", held, holder, err)
+	}
+	if held, _, err := a.TryAcquire("a"); !held || err != nil {
+		t.Fatalf("a could not renew: %v", err)
+	}
+
+	// a stops renewing; once b saw no change for the lease b takes over
+	b.TryAcquire("b")
+	b.observedTime = b.observedTime.Add(-b.LeaseDuration - time.Second)
+	if held, _, err := b.TryAcquire("b"); !held || err != nil {
+		t.Fatalf("b did not take an expired lock: %v", err)
+	}
+	obj, _ := b.get()
+	_, rec, _ := b.record(obj)
+	if rec.HolderIdentity != "b" || rec.LeaderTransitions != 1 {
+		t.Errorf("record = %+v", rec)
+	}
+
+	if err := b.Release("b"); err != nil {
+		t.Fatal(err)
+	}
+	if held, _, err := a.TryAcquire("a"); !held || err != nil {
+		t.Errorf("a could not take a released lock: %v", err)
+	}
+}
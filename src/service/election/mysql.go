@@ -0,0 +1,77 @@
+package election
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync"
+)
+
+// MysqlLock is a MySQL advisory lock, GET_LOCK. MySQL ties the lock to the
+// session taking it, so the leader keeps a connection of its own open; the
+// lock is freed when that connection dies.
+type MysqlLock struct {
+	Db   *sql.DB
+	Name string
+
+	lock sync.Mutex
+	conn *sql.Conn
+}
+
+func NewMysqlLock(db *sql.DB, name string) *MysqlLock {
+	return &MysqlLock{Db: db, Name: name}
+}
+
+func (l *MysqlLock) TryAcquire(identity string) (bool, string, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	ctx := context.Background()
+	if l.conn != nil {
+		var mine sql.NullBool
+		err := l.conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", l.Name).Scan(&mine)
+		if err == nil && mine.Valid && mine.Bool {
+			return true, identity, nil
+		}
+		l.conn.Close()
+		l.conn = nil
+		if err != nil {
+			return false, "", err
+		}
+	}
+	conn, err := l.Db.Conn(ctx)
+	if err != nil {
+		return false, "", err
+	}
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", l.Name).Scan(&got); err != nil {
+		conn.Close()
+		return false, "", err
+	}
+	if got.Valid && got.Int64 == 1 {
+		l.conn = conn
+		return true, identity, nil
+	}
+	var holder sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?)", l.Name).Scan(&holder)
+	conn.Close()
+	if err != nil || !holder.Valid {
+		return false, "", err
+	}
+	return false, "mysql connection " + strconv.FormatInt(holder.Int64, 10), nil
+}
+
+func (l *MysqlLock) Release(identity string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.conn == nil {
+		return nil
+	}
+	_, err := l.conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", l.Name)
+	l.conn.Close()
+	l.conn = nil
+	return err
+}
+
+func (l *MysqlLock) Describe() string {
+	return "mysql GET_LOCK " + l.Name
+}
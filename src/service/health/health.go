@@ -0,0 +1,64 @@
+// Package health runs the checks behind the liveness and readiness probes and
+// reports each of them, so a failing probe says what failed.
+package health
+
+import (
+	"fmt"
+	"time"
+)
+
+// Check is one probe condition. Run returns a detail worth showing when the
+// check passes, or why it failed.
+type Check struct {
+	Name string
+	Run  func() (string, error)
+}
+
+type Result struct {
+	Name   string `json:"name"`
+	Ok     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+	Took   string `json:"took"`
+}
+
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+func (r Report) Ok() bool {
+	return r.Status == "ok"
+}
+
+// Run runs the checks concurrently. A check still running after timeout
+// fails; it is left to finish on its own.
+func Run(checks []Check, timeout time.Duration) Report {
+	results := make([]chan Result, len(checks))
+	for i, c := range checks {
+		results[i] = make(chan Result, 1)
+		go func(c Check, out chan<- Result) {
+			start := time.Now()
+			detail, err := c.Run()
+			r := Result{Name: c.Name, Ok: err == nil, Detail: detail, Took: time.Since(start).String()}
+			if err != nil {
+				r.Detail = err.Error()
+			}
+			out <- r
+		}(c, results[i])
+	}
+	report := Report{Status: "ok"}
+	deadline := time.After(timeout)
+	for i, c := range checks {
+		var r Result
+		select {
+		case r = <-results[i]:
+		case <-deadline:
+			r = Result{Name: c.Name, Detail: fmt.Sprintf("no answer within %s", timeout), Took: timeout.String()}
+		}
+		if !r.Ok {
+			report.Status = "failed"
+		}
+		report.Checks = append(report.Checks, r)
+	}
+	return report
+}
@@ -0,0 +1,32 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	checks := []Check{
+		{Name: "ok", Run: func() (string, error) { return "fine", nil }},
+		{Name: "broken", Run: func() (string, error) { return "", errors.New("unreachable") }},
+		{Name: "hung", Run: func() (string, error) { <-hang; return "", nil }},
+	}
+	report := Run(checks, 50*time.Millisecond)
+	if report.Ok() || len(report.Checks) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	want := []Result{{Name: "ok", Ok: true, Detail: "fine"}, {Name: "broken", Detail: "unreachable"}, {Name: "hung", Detail: "no answer within 50ms"}}
+	for i, r := range report.Checks {
+		r.Took = ""
+		if r != want[i] {
+			t.Errorf("check %d = %+v, want %+v", i, r, want[i])
+		}
+	}
+
+	if report := Run(checks[:1], time.Second); !report.Ok() {
+		t.Errorf("report = %+v", report)
+	}
+}
@@ -0,0 +1,21 @@
+package query
+
+import (
+	model "model/collect"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// AuditLog returns the control calls made within w, newest first, at most
+// limit of them. caller narrows them to one caller when not empty.
+func AuditLog(w Window, caller string, limit int) ([]model.AuditLog, error) {
+	qs := orm.NewOrm().QueryTable("audit_log").Filter("Record_time__gte", w.From).Filter("Record_time__lt", w.To)
+	if caller != "" {
+		qs = qs.Filter("caller", caller)
+	}
+	rows := []model.AuditLog{}
+	if _, err := qs.OrderBy("-Record_time", "-id").Limit(limit).All(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
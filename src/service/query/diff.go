@@ -0,0 +1,151 @@
+package query
+
+import (
+	"fmt"
+	model "model/collect"
+	"sort"
+	"strings"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// diffKind describes how the history rows of one kind are compared: rows are
+// matched on the key columns and the field columns are compared.
+type diffKind struct {
+	table  string
+	key    []string
+	fields []string
+}
+
+var diffKinds = map[string]diffKind{
+	"pods": {
+		table:  "pods",
+		key:    []string{"Namespace", "Pod_name"},
+		fields: []string{"Node_name", "Pod_hostIP", "Pod_IP", "Phase", "Restart_count", "Qos_class",
+			"Containers_numbers", "Uid", "Create_time"},
+	},
+	"nodes": {
+		table:  "nodes",
+		key:    []string{"Node_name"},
+		fields: []string{"Numbers_cpu_core", "Numbers_gpu_core", "Memory_size", "Pod_limit",
+			"Allocatable_cpu", "Allocatable_gpu", "Allocatable_memory", "Allocatable_pods",
+			"Ready", "Unschedulable", "Kubelet_version", "Taints", "Create_time"},
+	},
+	"services": {
+		table:  "services",
+		key:    []string{"Namespace", "Service_name"},
+		fields: []string{"Type", "Cluster_ip", "Ports", "Selector", "External_ips", "Lb_ingress", "Uid", "Create_time"},
+	},
+	"workloads": {
+		table:  "workloads",
+		key:    []string{"Kind", "Namespace", "Workload_name"},
+		fields: []string{"Desired", "Current", "Updated", "Ready", "Available", "Generation", "Converged", "Paused",
+			"Selector", "Uid"},
+	},
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type ObjectChange struct {
+	Name    string        `json:"name"`
+	Changes []FieldChange `json:"changes"`
+}
+
+type KindDiff struct {
+	Added   []string       `json:"added"`
+	Removed []string       `json:"removed"`
+	Changed []ObjectChange `json:"changed"`
+}
+
+type SnapshotDiff struct {
+	From  *model.Runs          `json:"from"`
+	To    *model.Runs          `json:"to"`
+	Kinds map[string]*KindDiff `json:"kinds"`
+}
+
+// Diff compares the rows stored by two runs. kinds limits the comparison,
+// all kinds are compared when it is empty.
+func Diff(fromRef string, toRef string, kinds []string) (*SnapshotDiff, error) {
+	from, err := ResolveRun(fromRef)
+	if err != nil {
+		return nil, err
+	}
+	to, err := ResolveRun(toRef)
+	if err != nil {
+		return nil, err
+	}
+	if len(kinds) == 0 {
+		for kind := range diffKinds {
+			kinds = append(kinds, kind)
+		}
+	}
+	diff := &SnapshotDiff{From: from, To: to, Kinds: make(map[string]*KindDiff)}
+	for _, kind := range kinds {
+		dk, ok := diffKinds[kind]
+		if !ok {
+			return nil, ErrUnknownKind
+		}
+		before, err := loadSnapshot(dk, from.Tag)
+		if err != nil {
+			return nil, err
+		}
+		after, err := loadSnapshot(dk, to.Tag)
+		if err != nil {
+			return nil, err
+		}
+		diff.Kinds[kind] = diffSnapshots(before, after, dk.fields)
+	}
+	return diff, nil
+}
+
+func loadSnapshot(dk diffKind, tag string) (map[string]orm.Params, error) {
+	var rows []orm.Params
+	cols := append(append([]string{}, dk.key...), dk.fields...)
+	_, err := orm.NewOrm().QueryTable(dk.table).Filter("tag", tag).Limit(-1).Values(&rows, cols...)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(map[string]orm.Params, len(rows))
+	for _, row := range rows {
+		parts := make([]string, len(dk.key))
+		for i, k := range dk.key {
+			parts[i] = fmt.Sprint(row[k])
+		}
+		snapshot[strings.Join(parts, "/")] = row
+	}
+	return snapshot, nil
+}
+
+func diffSnapshots(before map[string]orm.Params, after map[string]orm.Params, fields []string) *KindDiff {
+	d := &KindDiff{Added: []string{}, Removed: []string{}, Changed: []ObjectChange{}}
+	for name, row := range after {
+		old, ok := before[name]
+		if !ok {
+			d.Added = append(d.Added, name)
+			continue
+		}
+		var changes []FieldChange
+		for _, f := range fields {
+			a, b := fmt.Sprint(old[f]), fmt.Sprint(row[f])
+			if a != b {
+				changes = append(changes, FieldChange{Field: f, From: a, To: b})
+			}
+		}
+		if len(changes) > 0 {
+			d.Changed = append(d.Changed, ObjectChange{Name: name, Changes: changes})
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].Name < d.Changed[j].Name })
+	return d
+}
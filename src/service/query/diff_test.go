@@ -0,0 +1,33 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/astaxie/beego/orm"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	before := map[string]orm.Params{
+		"web-1": {"Pod_name": "web-1", "Pod_hostIP": "10.0.0.1", "Restart_count": "0"},
+		"web-2": {"Pod_name": "web-2", "Pod_hostIP": "10.0.0.2", "Restart_count": "1"},
+		"db-0":  {"Pod_name": "db-0", "Pod_hostIP": "10.0.0.3", "Restart_count": "0"},
+	}
+	after := map[string]orm.Params{
+		"web-1": {"Pod_name": "web-1", "Pod_hostIP": "10.0.0.1", "Restart_count": "0"},
+		"web-2": {"Pod_name": "web-2", "Pod_hostIP": "10.0.0.4", "Restart_count": "3"},
+		"web-3": {"Pod_name": "web-3", "Pod_hostIP": "10.0.0.1", "Restart_count": "0"},
+	}
+	d := diffSnapshots(before, after, []string{"Pod_hostIP", "Restart_count"})
+	if len(d.Added) != 1 || d.Added[0] != "web-3" {
+		t.Errorf("added = %v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0] != "db-0" {
+		t.Errorf("removed = %v", d.Removed)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].Name != "web-2" || len(d.Changed[0].Changes) != 2 {
+		t.Fatalf("changed = %+v", d.Changed)
+	}
+	if c := d.Changed[0].Changes[0]; c.Field != "Pod_hostIP" || c.From != "10.0.0.2" || c.To != "10.0.0.4" {
+		t.Errorf("first change = %+v", c)
+	}
+}
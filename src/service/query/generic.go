@@ -0,0 +1,77 @@
+package query
+
+import (
+	"encoding/json"
+	model "model/collect"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// GenericObject is a generically collected object with its extracted fields.
+type GenericObject struct {
+	Namespace        string            `json:"namespace"`
+	Name             string            `json:"name"`
+	Uid              string            `json:"uid"`
+	Resource_version string            `json:"resource_version"`
+	Labels           string            `json:"labels"`
+	Fields           map[string]string `json:"fields"`
+	Document         json.RawMessage   `json:"document"`
+}
+
+// GenericObjects lists the objects of a generic kind recorded by a run whose
+// extracted fields equal the values in match.
+func GenericObjects(kind string, runRef string, namespace string, match map[string]string) (*RunSet, []GenericObject, error) {
+	run, err := resolveRunOrLatest(runRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	o := orm.NewOrm()
+	var fields []model.GenericFields
+	if _, err := o.QueryTable("generic_fields").Filter("tag__in", run.Tags()).Filter("kind", kind).Limit(-1).All(&fields); err != nil {
+		return nil, nil, err
+	}
+	byUid := make(map[string]map[string]string)
+	for _, f := range fields {
+		if byUid[f.Uid] == nil {
+			byUid[f.Uid] = make(map[string]string)
+		}
+		byUid[f.Uid][f.Field] = f.Value
+	}
+	qs := o.QueryTable("generic_objects").Filter("tag__in", run.Tags()).Filter("kind", kind)
+	if namespace != "" {
+		qs = qs.Filter("namespace", namespace)
+	}
+	var rows []model.GenericObjects
+	if _, err := qs.OrderBy("namespace", "name").Limit(-1).All(&rows); err != nil {
+		return nil, nil, err
+	}
+	objects := []GenericObject{}
+	for _, row := range rows {
+		values := byUid[row.Uid]
+		if !fieldsMatch(values, match) {
+			continue
+		}
+		if values == nil {
+			values = map[string]string{}
+		}
+		objects = append(objects, GenericObject{
+			Namespace:        row.Namespace,
+			Name:             row.Name,
+			Uid:              row.Uid,
+			Resource_version: row.Resource_version,
+			Labels:           row.Labels,
+			Fields:           values,
+			Document:         json.RawMessage(row.Document),
+		})
+	}
+	return run, objects, nil
+}
+
+func fieldsMatch(values map[string]string, match map[string]string) bool {
+	for field, want := range match {
+		if v, ok := values[field]; !ok || v != want {
+			return false
+		}
+	}
+	return true
+}
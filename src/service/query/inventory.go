@@ -0,0 +1,175 @@
+package query
+
+import (
+	"common"
+	"math"
+	model "model/collect"
+	"sort"
+	"time"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// NotFoundError is returned when a query names a kind or run that does not
+// exist.
+type NotFoundError string
+
+func (e NotFoundError) Error() string {
+	return string(e)
+}
+
+var ErrUnknownKind = NotFoundError("unknown resource kind")
+
+// IncompleteError is returned when a run lacks the data a report needs, so
+// the report cannot be told.
+type IncompleteError string
+
+func (e IncompleteError) Error() string {
+	return string(e)
+}
+
+// Window is a time range in record time, From inclusive and To exclusive.
+type Window struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NewWindow parses from and to, defaulting to the last day.
+func NewWindow(from string, to string) (Window, error) {
+	end := time.Now()
+	if to != "" {
+		t, err := common.ParseRecordTime(to)
+		if err != nil {
+			return Window{}, err
+		}
+		end = t
+	}
+	start := end.Add(-24 * time.Hour)
+	if from != "" {
+		t, err := common.ParseRecordTime(from)
+		if err != nil {
+			return Window{}, err
+		}
+		start = t
+	}
+	return Window{From: common.RecordTime(start), To: common.RecordTime(end)}, nil
+}
+
+func inventoryTable(kind string) (string, error) {
+	table, ok := model.InventoryTables[kind]
+	if !ok {
+		return "", ErrUnknownKind
+	}
+	return table, nil
+}
+
+// Inventory lists the current-state rows of kind. state is "alive", "deleted"
+// or empty for both.
+func Inventory(kind string, state string, namespace string) ([]orm.Params, error) {
+	table, err := inventoryTable(kind)
+	if err != nil {
+		return nil, err
+	}
+	qs := orm.NewOrm().QueryTable(table)
+	switch state {
+	case "alive":
+		qs = qs.Filter("Deleted_at", "")
+	case "deleted":
+		qs = qs.Exclude("Deleted_at", "")
+	}
+	if namespace != "" {
+		qs = qs.Filter("namespace", namespace)
+	}
+	var rows []orm.Params
+	_, err = qs.OrderBy("namespace", "name").Limit(-1).Values(&rows)
+	return rows, err
+}
+
+type ChurnRow struct {
+	Namespace string `json:"namespace"`
+	Created   int64  `json:"created"`
+	Deleted   int64  `json:"deleted"`
+	Alive     int64  `json:"alive"`
+}
+
+// Churn counts per namespace the objects of kind first seen and deleted within
+// w, and those alive at its end.
+func Churn(kind string, w Window) ([]ChurnRow, error) {
+	table, err := inventoryTable(kind)
+	if err != nil {
+		return nil, err
+	}
+	var rows []ChurnRow
+	_, err = orm.NewOrm().Raw("SELECT `namespace`,"+
+		" SUM(`First_seen` >= ? AND `First_seen` < ?) AS `created`,"+
+		" SUM(`Deleted_at` >= ? AND `Deleted_at` < ?) AS `deleted`,"+
+		" SUM(`First_seen` < ? AND (`Deleted_at` = '' OR `Deleted_at` >= ?)) AS `alive`"+
+		" FROM `"+table+"` GROUP BY `namespace` ORDER BY `namespace`",
+		w.From, w.To, w.From, w.To, w.To, w.To).QueryRows(&rows)
+	return rows, err
+}
+
+type LifetimeRow struct {
+	Namespace string  `json:"namespace"`
+	Count     int     `json:"count"`
+	Min       int64   `json:"min_seconds"`
+	Mean      float64 `json:"mean_seconds"`
+	P50       int64   `json:"p50_seconds"`
+	P90       int64   `json:"p90_seconds"`
+	Max       int64   `json:"max_seconds"`
+}
+
+// PodLifetime summarises the lifetime of the pods deleted within w, per
+// namespace and over all namespaces (Namespace "*").
+func PodLifetime(w Window, namespace string) ([]LifetimeRow, error) {
+	qs := orm.NewOrm().QueryTable(model.InventoryTables["pods"]).
+		Filter("Deleted_at__gte", w.From).Filter("Deleted_at__lt", w.To)
+	if namespace != "" {
+		qs = qs.Filter("namespace", namespace)
+	}
+	var pods []model.PodInventory
+	if _, err := qs.Limit(-1).All(&pods, "namespace", "Lifetime"); err != nil {
+		return nil, err
+	}
+	byNamespace := make(map[string][]int64)
+	var all []int64
+	for _, p := range pods {
+		byNamespace[p.Namespace] = append(byNamespace[p.Namespace], p.Lifetime)
+		all = append(all, p.Lifetime)
+	}
+	rows := make([]LifetimeRow, 0, len(byNamespace)+1)
+	for ns, lifetimes := range byNamespace {
+		rows = append(rows, lifetimeRow(ns, lifetimes))
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Namespace < rows[j].Namespace })
+	if len(all) > 0 {
+		rows = append(rows, lifetimeRow("*", all))
+	}
+	return rows, nil
+}
+
+func lifetimeRow(namespace string, lifetimes []int64) LifetimeRow {
+	sort.Slice(lifetimes, func(i, j int) bool { return lifetimes[i] < lifetimes[j] })
+	var sum int64
+	for _, l := range lifetimes {
+		sum += l
+	}
+	return LifetimeRow{
+		Namespace: namespace,
+		Count:     len(lifetimes),
+		Min:       lifetimes[0],
+		Mean:      float64(sum) / float64(len(lifetimes)),
+		P50:       percentile(lifetimes, 0.5),
+		P90:       percentile(lifetimes, 0.9),
+		Max:       lifetimes[len(lifetimes)-1],
+	}
+}
+
+// percentile uses the nearest-rank method on sorted values.
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
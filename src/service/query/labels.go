@@ -0,0 +1,208 @@
+package query
+
+import (
+	model "model/collect"
+	"sort"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// LabeledObject is one object of a run with its labels and stored
+// annotations.
+type LabeledObject struct {
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name"`
+	Uid         string            `json:"uid"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Objects lists the objects of kind recorded by a run whose labels match
+// selector. The objects are read from the table of the kind, so the ones
+// without any label are matched against an empty label set.
+func Objects(kind string, runRef string, selector string, namespace string) (*RunSet, []LabeledObject, error) {
+	s, err := ParseSelector(selector)
+	if err != nil {
+		return nil, nil, err
+	}
+	run, err := resolveRunOrLatest(runRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	objects, err := labeledObjects(kind, run.Tags(), namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	return run, selectObjects(objects, s), nil
+}
+
+// selectObjects keeps the objects whose labels match s, by namespace and
+// name.
+func selectObjects(objects map[string]*LabeledObject, s Selector) []LabeledObject {
+	matched := []LabeledObject{}
+	for _, o := range objects {
+		if s.Matches(o.Labels) {
+			matched = append(matched, *o)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Namespace != matched[j].Namespace {
+			return matched[i].Namespace < matched[j].Namespace
+		}
+		return matched[i].Name < matched[j].Name
+	})
+	return matched
+}
+
+// objectTable tells where the objects of a labeled kind are recorded. Kind
+// narrows tables shared by several kinds; namespace and uid are empty when
+// the table has no such column.
+type objectTable struct {
+	table     string
+	kind      string
+	namespace string
+	name      string
+	uid       string
+}
+
+var objectTables = map[string]objectTable{
+	"pods":                   {table: "pods", namespace: "namespace", name: "pod_name", uid: "uid"},
+	"nodes":                  {table: "nodes", name: "Node_name"},
+	"services":               {table: "services", namespace: "namespace", name: "Service_name", uid: "uid"},
+	"endpoints":              {table: "service_endpoints", namespace: "namespace", name: "Service_name"},
+	"namespaces":             {table: "namespaces", name: "namespace"},
+	"resourcequotas":         {table: "quotas", namespace: "namespace", name: "quota_name"},
+	"limitranges":            {table: "limit_ranges", namespace: "namespace", name: "limit_name"},
+	"persistentvolumes":      {table: "persistent_volumes", name: "volume_name"},
+	"persistentvolumeclaims": {table: "persistent_volume_claims", namespace: "namespace", name: "claim_name"},
+	"replicationcontrollers": {table: "workloads", kind: "ReplicationController", namespace: "namespace", name: "workload_name", uid: "uid"},
+	"replicasets":            {table: "workloads", kind: "ReplicaSet", namespace: "namespace", name: "workload_name", uid: "uid"},
+	"deployments":            {table: "workloads", kind: "Deployment", namespace: "namespace", name: "workload_name", uid: "uid"},
+	"daemonsets":             {table: "workloads", kind: "DaemonSet", namespace: "namespace", name: "workload_name", uid: "uid"},
+	"statefulsets":           {table: "workloads", kind: "StatefulSet", namespace: "namespace", name: "workload_name", uid: "uid"},
+	"jobs":                   {table: "workloads", kind: "Job", namespace: "namespace", name: "workload_name", uid: "uid"},
+}
+
+// recordedObjects lists the objects of kind a run recorded, labeled or not.
+// Kinds without a table of their own are generic ones.
+func recordedObjects(kind string, tags []string, namespace string) ([]LabeledObject, error) {
+	t, ok := objectTables[kind]
+	if !ok {
+		t = objectTable{table: "generic_objects", kind: kind, namespace: "namespace", name: "name", uid: "uid"}
+	}
+	qs := orm.NewOrm().QueryTable(t.table).Filter("tag__in", tags)
+	if t.kind != "" {
+		qs = qs.Filter("kind", t.kind)
+	}
+	if namespace != "" {
+		if t.namespace == "" {
+			return nil, nil
+		}
+		qs = qs.Filter(t.namespace, namespace)
+	}
+	columns := []string{t.name, t.namespace, t.uid}
+	var rows []orm.ParamsList
+	if _, err := qs.Limit(-1).ValuesList(&rows, nonEmpty(columns)...); err != nil {
+		return nil, err
+	}
+	objects := make([]LabeledObject, 0, len(rows))
+	for _, row := range rows {
+		var o LabeledObject
+		o.Name, _ = row[0].(string)
+		if t.namespace != "" {
+			o.Namespace, _ = row[1].(string)
+		}
+		if t.uid != "" {
+			o.Uid, _ = row[len(row)-1].(string)
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+func nonEmpty(list []string) []string {
+	var kept []string
+	for _, s := range list {
+		if s != "" {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+func labeledObjects(kind string, tags []string, namespace string) (map[string]*LabeledObject, error) {
+	objects, err := recordedObjects(kind, tags, namespace)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := objectLabels(kind, tags, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return joinLabels(objects, rows), nil
+}
+
+func objectLabels(kind string, tags []string, namespace string) ([]model.ObjectLabels, error) {
+	qs := orm.NewOrm().QueryTable("object_labels").Filter("tag__in", tags).Filter("kind", kind)
+	if namespace != "" {
+		qs = qs.Filter("namespace", namespace)
+	}
+	var rows []model.ObjectLabels
+	_, err := qs.Limit(-1).All(&rows)
+	return rows, err
+}
+
+// joinLabels keys objects by namespace/name and puts the label rows on them.
+// Every object gets a label map, empty when it has no labels; a label row
+// of an object missing from objects adds the object.
+func joinLabels(objects []LabeledObject, rows []model.ObjectLabels) map[string]*LabeledObject {
+	joined := make(map[string]*LabeledObject, len(objects))
+	for i := range objects {
+		o := objects[i]
+		o.Labels = map[string]string{}
+		joined[o.Namespace+"/"+o.Name] = &o
+	}
+	for _, row := range rows {
+		key := row.Namespace + "/" + row.Name
+		o, ok := joined[key]
+		if !ok {
+			o = &LabeledObject{Namespace: row.Namespace, Name: row.Name, Labels: map[string]string{}}
+			joined[key] = o
+		}
+		if o.Uid == "" {
+			o.Uid = row.Uid
+		}
+		if row.Annotation {
+			if o.Annotations == nil {
+				o.Annotations = map[string]string{}
+			}
+			o.Annotations[row.Key] = row.Value
+		} else {
+			o.Labels[row.Key] = row.Value
+		}
+	}
+	return joined
+}
+
+// selectPods keeps the pods whose labels match selector.
+func selectPods(pods []model.Pods, tags []string, selector Selector) ([]model.Pods, error) {
+	if len(selector) == 0 {
+		return pods, nil
+	}
+	rows, err := objectLabels("pods", tags, "")
+	if err != nil {
+		return nil, err
+	}
+	objects := joinLabels(nil, rows)
+	matched := []model.Pods{}
+	for _, pod := range pods {
+		labels := map[string]string{}
+		if o, ok := objects[pod.Namespace+"/"+pod.Pod_name]; ok {
+			labels = o.Labels
+		}
+		if selector.Matches(labels) {
+			matched = append(matched, pod)
+		}
+	}
+	return matched, nil
+}
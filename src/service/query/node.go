@@ -0,0 +1,94 @@
+package query
+
+import (
+	model "model/collect"
+
+	"github.com/astaxie/beego/orm"
+)
+
+type VersionReport struct {
+	Run        *RunSet             `json:"run"`
+	Skew       bool                `json:"skew"`
+	Kubelet    map[string][]string `json:"kubelet"`
+	Kube_proxy map[string][]string `json:"kube_proxy"`
+	Runtime    map[string][]string `json:"runtime"`
+	Kernel     map[string][]string `json:"kernel"`
+	Os_image   map[string][]string `json:"os_image"`
+}
+
+// NodeVersions groups the nodes of a run by component version. Skew is set
+// when the nodes run more than one kubelet or kube-proxy version.
+func NodeVersions(runRef string) (*VersionReport, error) {
+	run, err := resolveRunOrLatest(runRef)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []model.Nodes
+	if _, err := orm.NewOrm().QueryTable("nodes").Filter("tag__in", run.Tags()).OrderBy("Node_name").Limit(-1).All(&nodes); err != nil {
+		return nil, err
+	}
+	report := &VersionReport{
+		Run:        run,
+		Kubelet:    make(map[string][]string),
+		Kube_proxy: make(map[string][]string),
+		Runtime:    make(map[string][]string),
+		Kernel:     make(map[string][]string),
+		Os_image:   make(map[string][]string),
+	}
+	for _, n := range nodes {
+		report.Kubelet[n.Kubelet_version] = append(report.Kubelet[n.Kubelet_version], n.Node_name)
+		report.Kube_proxy[n.Kube_proxy_version] = append(report.Kube_proxy[n.Kube_proxy_version], n.Node_name)
+		report.Runtime[n.Runtime_version] = append(report.Runtime[n.Runtime_version], n.Node_name)
+		report.Kernel[n.Kernel_version] = append(report.Kernel[n.Kernel_version], n.Node_name)
+		report.Os_image[n.Os_image] = append(report.Os_image[n.Os_image], n.Node_name)
+	}
+	report.Skew = len(report.Kubelet) > 1 || len(report.Kube_proxy) > 1
+	return report, nil
+}
+
+// ConditionPeriod is a stretch of time a node condition kept one status.
+type ConditionPeriod struct {
+	Node_name  string `json:"node_name"`
+	Type       string `json:"type"`
+	Status     string `json:"status"`
+	Reason     string `json:"reason"`
+	Since      string `json:"since"`
+	First_seen string `json:"first_seen"`
+	Last_seen  string `json:"last_seen"`
+}
+
+// NodeConditionHistory turns the condition rows recorded within w into
+// periods per node. conditionType defaults to Ready; node narrows the result
+// to one node when not empty.
+func NodeConditionHistory(node string, conditionType string, w Window) ([]ConditionPeriod, error) {
+	if conditionType == "" {
+		conditionType = string(model.NodeReady)
+	}
+	qs := orm.NewOrm().QueryTable("node_conditions").Filter("type", conditionType).
+		Filter("Record_time__gte", w.From).Filter("Record_time__lt", w.To)
+	if node != "" {
+		qs = qs.Filter("Node_name", node)
+	}
+	var rows []model.NodeConditions
+	if _, err := qs.OrderBy("Node_name", "Record_time").Limit(-1).All(&rows); err != nil {
+		return nil, err
+	}
+	periods := []ConditionPeriod{}
+	for _, r := range rows {
+		last := len(periods) - 1
+		if last >= 0 && periods[last].Node_name == r.Node_name && periods[last].Status == r.Status {
+			periods[last].Last_seen = r.Record_time
+			continue
+		}
+		periods = append(periods, ConditionPeriod{
+			Node_name:  r.Node_name,
+			Type:       r.Type,
+			Status:     r.Status,
+			Reason:     r.Reason,
+			Since:      r.Last_transition_time,
+			First_seen: r.Record_time,
+			Last_seen:  r.Record_time,
+		})
+	}
+	return periods, nil
+}
@@ -0,0 +1,42 @@
+package query
+
+import (
+	model "model/collect"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// podFilters maps the accepted filter names to Pods columns.
+var podFilters = map[string]string{
+	"namespace": "namespace",
+	"node":      "node_name",
+	"phase":     "phase",
+	"qos":       "qos_class",
+	"owner":     "owner_name",
+	"ownerkind": "owner_kind",
+}
+
+// Pods lists the pod rows of a run, narrowed by the non-empty filters and
+// the label selector.
+func Pods(runRef string, filters map[string]string, selector string) (*RunSet, []model.Pods, error) {
+	s, err := ParseSelector(selector)
+	if err != nil {
+		return nil, nil, err
+	}
+	run, err := resolveRunOrLatest(runRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	qs := orm.NewOrm().QueryTable("pods").Filter("tag__in", run.Tags())
+	for name, column := range podFilters {
+		if value := filters[name]; value != "" {
+			qs = qs.Filter(column, value)
+		}
+	}
+	var pods []model.Pods
+	if _, err = qs.OrderBy("namespace", "pod_name").Limit(-1).All(&pods); err != nil {
+		return nil, nil, err
+	}
+	pods, err = selectPods(pods, run.Tags(), s)
+	return run, pods, err
+}
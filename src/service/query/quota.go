@@ -0,0 +1,36 @@
+package query
+
+import (
+	"github.com/astaxie/beego/orm"
+)
+
+type QuotaUsageRow struct {
+	Namespace   string   `json:"namespace"`
+	Quota_name  string   `json:"quota_name"`
+	Resource    string   `json:"resource"`
+	Hard        string   `json:"hard"`
+	Used        string   `json:"used"`
+	Ratio       *float64 `json:"ratio"`
+	Record_time string   `json:"Record_time"`
+	Tag         string   `json:"tag"`
+}
+
+// QuotaUsage returns the quota usage time series within w from the quota_usage
+// view. namespace and resource narrow the result when not empty.
+func QuotaUsage(namespace string, resource string, w Window) ([]QuotaUsageRow, error) {
+	sql := "SELECT `namespace`, `quota_name`, `resource`, `hard`, `used`, `ratio`, `Record_time`, `tag`" +
+		" FROM `quota_usage` WHERE `Record_time` >= ? AND `Record_time` < ?"
+	args := []interface{}{w.From, w.To}
+	if namespace != "" {
+		sql += " AND `namespace` = ?"
+		args = append(args, namespace)
+	}
+	if resource != "" {
+		sql += " AND `resource` = ?"
+		args = append(args, resource)
+	}
+	sql += " ORDER BY `namespace`, `resource`, `quota_name`, `Record_time`"
+	var rows []QuotaUsageRow
+	_, err := orm.NewOrm().Raw(sql, args...).QueryRows(&rows)
+	return rows, err
+}
@@ -0,0 +1,156 @@
+package query
+
+import (
+	"common"
+	"fmt"
+	model "model/collect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// ResolveRun finds a collection run by id, by tag, or as the latest run started
+// at or before a time.
+func ResolveRun(ref string) (*model.Runs, error) {
+	o := orm.NewOrm()
+	run := &model.Runs{}
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		run.Id = id
+		if err := o.Read(run); err != nil {
+			return nil, runError(ref, err)
+		}
+		return run, nil
+	}
+	if err := o.QueryTable("runs").Filter("tag", ref).One(run); err == nil {
+		return run, nil
+	} else if err != orm.ErrNoRows {
+		return nil, err
+	}
+	t, err := common.ParseRecordTime(ref)
+	if err != nil {
+		return nil, NotFoundError(fmt.Sprintf("collection run not found: %q is neither a run id, a tag nor a time", ref))
+	}
+	err = o.QueryTable("runs").Filter("Start_time__lte", common.RecordTime(t)).OrderBy("-Start_time").One(run)
+	if err != nil {
+		return nil, runError(ref, err)
+	}
+	return run, nil
+}
+
+func runError(ref string, err error) error {
+	if err == orm.ErrNoRows {
+		return NotFoundError("collection run not found: " + ref)
+	}
+	return err
+}
+
+// RunSet is the runs a query reads: one run, or with sharding the latest run
+// of every shard, which together cover the cluster. Run is the newest of
+// them; its fields are the ones shown for the set.
+type RunSet struct {
+	*model.Runs
+	Shards []*model.Runs `json:"shards,omitempty"`
+}
+
+// Tags returns the tags of the runs in the set.
+func (s *RunSet) Tags() []string {
+	if len(s.Shards) == 0 {
+		return []string{s.Tag}
+	}
+	tags := make([]string, len(s.Shards))
+	for i, run := range s.Shards {
+		tags[i] = run.Tag
+	}
+	return tags
+}
+
+// shardWindow is how much older than the newest shard run the run of
+// another shard may be and still count as current; a replica that has not
+// finished a run for that long is gone.
+const shardWindow = 10 * time.Minute
+
+// LatestRun returns the most recent run that has finished. When a shard
+// collected it, the latest finished run of each other shard of the same
+// size, in the same cluster and within shardWindow, joins it.
+func LatestRun() (*RunSet, error) {
+	run := &model.Runs{}
+	qs := orm.NewOrm().QueryTable("runs").Exclude("End_time", "")
+	err := qs.OrderBy("-Start_time").One(run)
+	if err != nil {
+		return nil, runError("latest", err)
+	}
+	if run.Shard == "" {
+		return &RunSet{Runs: run}, nil
+	}
+	start, err := common.ParseRecordTime(run.Start_time)
+	if err != nil {
+		return nil, err
+	}
+	var recent []model.Runs
+	_, err = qs.Filter("Cluster", run.Cluster).Filter("Start_time__gte", common.RecordTime(start.Add(-shardWindow))).
+		OrderBy("-Start_time").Limit(-1).All(&recent)
+	if err != nil {
+		return nil, err
+	}
+	return latestOfEachShard(run, recent), nil
+}
+
+// latestOfEachShard picks from recent, newest first, the first run of every
+// shard sized like newest's, at most as many as there are shards.
+func latestOfEachShard(newest *model.Runs, recent []model.Runs) *RunSet {
+	set := &RunSet{Runs: newest, Shards: []*model.Runs{newest}}
+	identity, size := splitShard(newest.Shard)
+	seen := map[string]bool{identity: true}
+	for i := range recent {
+		id, n := splitShard(recent[i].Shard)
+		if n != size || seen[id] || len(seen) >= shardCount(size) {
+			continue
+		}
+		seen[id] = true
+		set.Shards = append(set.Shards, &recent[i])
+	}
+	return set
+}
+
+// splitShard splits the shard of a run, "identity of members".
+func splitShard(shard string) (string, string) {
+	i := strings.LastIndex(shard, " of ")
+	if i < 0 {
+		return shard, ""
+	}
+	return shard[:i], shard[i+len(" of "):]
+}
+
+func shardCount(size string) int {
+	n, err := strconv.Atoi(size)
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// resolveRunOrLatest resolves ref, or the latest runs when ref is empty. A
+// ref names one run, with sharding the share of one shard.
+func resolveRunOrLatest(ref string) (*RunSet, error) {
+	if ref == "" {
+		return LatestRun()
+	}
+	run, err := ResolveRun(ref)
+	if err != nil {
+		return nil, err
+	}
+	return &RunSet{Runs: run}, nil
+}
+
+// RunDetail resolves a run and loads its per collector results.
+func RunDetail(ref string) (*RunSet, []model.RunResults, error) {
+	run, err := resolveRunOrLatest(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	var results []model.RunResults
+	_, err = orm.NewOrm().QueryTable("run_results").Filter("tag__in", run.Tags()).OrderBy("kind").All(&results)
+	return run, results, err
+}
@@ -0,0 +1,32 @@
+package query
+
+import (
+	model "model/collect"
+	"reflect"
+	"testing"
+)
+
+func TestLatestOfEachShard(t *testing.T) {
+	recent := []model.Runs{
+		{Tag: "a3", Shard: "a of 3"},
+		{Tag: "c2", Shard: "c of 3"},
+		{Tag: "a2", Shard: "a of 3"},
+		// before c joined
+		{Tag: "b9", Shard: "b of 2"},
+		{Tag: "b1", Shard: "b of 3"},
+		{Tag: "c1", Shard: "c of 3"},
+		{Tag: "d1", Shard: "d of 3"},
+	}
+	set := latestOfEachShard(&recent[0], recent)
+	if got := set.Tags(); !reflect.DeepEqual(got, []string{"a3", "c2", "b1"}) {
+		t.Errorf("tags = %v", got)
+	}
+	if set.Tag != "a3" {
+		t.Errorf("the set shows run %s", set.Tag)
+	}
+
+	single := &RunSet{Runs: &model.Runs{Tag: "x"}}
+	if got := single.Tags(); !reflect.DeepEqual(got, []string{"x"}) {
+		t.Errorf("tags = %v", got)
+	}
+}
@@ -0,0 +1,215 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Selector operators, following the Kubernetes label selector syntax.
+const (
+	SelectorExists       = "exists"
+	SelectorDoesNotExist = "!"
+	SelectorEquals       = "="
+	SelectorNotEquals    = "!="
+	SelectorIn           = "in"
+	SelectorNotIn        = "notin"
+)
+
+// Requirement is one comma separated term of a selector.
+type Requirement struct {
+	Key      string
+	Operator string
+	Values   []string
+}
+
+// Selector matches a label set when all its requirements do. The empty
+// selector matches everything.
+type Selector []Requirement
+
+// SelectorError reports a selector that does not parse.
+type SelectorError string
+
+func (e SelectorError) Error() string {
+	return "invalid label selector: " + string(e)
+}
+
+// ParseSelector parses selectors such as "app=web,tier in (fe,be),!canary".
+func ParseSelector(s string) (Selector, error) {
+	p := &selectorParser{input: s}
+	var selector Selector
+	p.skipSpace()
+	if p.done() {
+		return selector, nil
+	}
+	for {
+		r, err := p.requirement()
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, r)
+		p.skipSpace()
+		if p.done() {
+			return selector, nil
+		}
+		if !p.accept(",") {
+			return nil, p.errorf("expected ','")
+		}
+	}
+}
+
+// Matches reports whether labels satisfy every requirement.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case SelectorExists:
+		return ok
+	case SelectorDoesNotExist:
+		return !ok
+	case SelectorEquals, SelectorIn:
+		return ok && containsValue(r.Values, value)
+	case SelectorNotEquals, SelectorNotIn:
+		return !ok || !containsValue(r.Values, value)
+	}
+	return false
+}
+
+func containsValue(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Selector) String() string {
+	terms := make([]string, len(s))
+	for i, r := range s {
+		switch r.Operator {
+		case SelectorExists:
+			terms[i] = r.Key
+		case SelectorDoesNotExist:
+			terms[i] = "!" + r.Key
+		case SelectorIn, SelectorNotIn:
+			terms[i] = r.Key + " " + r.Operator + " (" + strings.Join(r.Values, ",") + ")"
+		default:
+			terms[i] = r.Key + r.Operator + r.Values[0]
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+type selectorParser struct {
+	input string
+	pos   int
+}
+
+func (p *selectorParser) requirement() (Requirement, error) {
+	var r Requirement
+	p.skipSpace()
+	if p.accept("!") {
+		key := p.word()
+		if key == "" {
+			return r, p.errorf("expected key after '!'")
+		}
+		return Requirement{Key: key, Operator: SelectorDoesNotExist}, nil
+	}
+	r.Key = p.word()
+	if r.Key == "" {
+		return r, p.errorf("expected key")
+	}
+	p.skipSpace()
+	switch {
+	case p.done() || p.peek(","):
+		r.Operator = SelectorExists
+		return r, nil
+	case p.accept("!="):
+		r.Operator = SelectorNotEquals
+	case p.accept("=="), p.accept("="):
+		r.Operator = SelectorEquals
+	default:
+		op := p.word()
+		if op != SelectorIn && op != SelectorNotIn {
+			return r, p.errorf("unknown operator %q", op)
+		}
+		r.Operator = op
+		values, err := p.valueSet()
+		if err != nil {
+			return r, err
+		}
+		r.Values = values
+		return r, nil
+	}
+	p.skipSpace()
+	r.Values = []string{p.word()}
+	return r, nil
+}
+
+func (p *selectorParser) valueSet() ([]string, error) {
+	p.skipSpace()
+	if !p.accept("(") {
+		return nil, p.errorf("expected '('")
+	}
+	var values []string
+	for {
+		p.skipSpace()
+		values = append(values, p.word())
+		p.skipSpace()
+		if p.accept(")") {
+			return values, nil
+		}
+		if !p.accept(",") {
+			return nil, p.errorf("expected ',' or ')'")
+		}
+	}
+}
+
+// word reads a key or value, made of the characters label keys and values
+// may contain.
+func (p *selectorParser) word() string {
+	start := p.pos
+	for !p.done() {
+		c := rune(p.input[p.pos])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune("-_./", c) {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *selectorParser) skipSpace() {
+	for !p.done() && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *selectorParser) peek(s string) bool {
+	return strings.HasPrefix(p.input[p.pos:], s)
+}
+
+func (p *selectorParser) accept(s string) bool {
+	if p.peek(s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *selectorParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *selectorParser) errorf(format string, args ...interface{}) error {
+	return SelectorError(fmt.Sprintf("%s at %d", fmt.Sprintf(format, args...), p.pos))
+}
@@ -0,0 +1,83 @@
+package query
+
+import (
+	model "model/collect"
+	"strings"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	s, err := ParseSelector("app=web, tier in (fe, be),!canary,track!=beta,env notin (dev),owner,release==v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.String(); got != "app=web,tier in (fe,be),!canary,track!=beta,env notin (dev),owner,release=v1" {
+		t.Errorf("String() = %q", got)
+	}
+	labels := map[string]string{"app": "web", "tier": "fe", "env": "prod", "owner": "ops", "release": "v1"}
+	if !s.Matches(labels) {
+		t.Errorf("selector should match %v", labels)
+	}
+	for key, value := range map[string]string{"app": "db", "tier": "cache", "canary": "true", "track": "beta", "env": "dev"} {
+		changed := map[string]string{}
+		for k, v := range labels {
+			changed[k] = v
+		}
+		changed[key] = value
+		if s.Matches(changed) {
+			t.Errorf("selector should not match %s=%s", key, value)
+		}
+	}
+	delete(labels, "owner")
+	if s.Matches(labels) {
+		t.Errorf("selector should require owner")
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, bad := range []string{"app=web,", "!", "tier in fe", "tier in (fe", "app>1", "a=b c"} {
+		if _, err := ParseSelector(bad); err == nil {
+			t.Errorf("ParseSelector(%q) should fail", bad)
+		} else if _, ok := err.(SelectorError); !ok {
+			t.Errorf("ParseSelector(%q) error %T", bad, err)
+		}
+	}
+	if s, err := ParseSelector(" "); err != nil || len(s) != 0 {
+		t.Errorf("empty selector = %v, %v", s, err)
+	}
+}
+
+func TestSelectUnlabeledObjects(t *testing.T) {
+	objects := joinLabels([]LabeledObject{
+		{Namespace: "default", Name: "web-1", Uid: "u1"},
+		{Namespace: "default", Name: "web-2", Uid: "u2"},
+		{Namespace: "default", Name: "batch", Uid: "u3"},
+	}, []model.ObjectLabels{
+		{Namespace: "default", Name: "web-1", Key: "app", Value: "web"},
+		{Namespace: "default", Name: "web-2", Key: "app", Value: "web"},
+		{Namespace: "default", Name: "web-2", Key: "canary", Value: "true"},
+	})
+	for selector, want := range map[string]string{
+		"":                     "batch,web-1,web-2",
+		"app=web":              "web-1,web-2",
+		"!canary":              "batch,web-1",
+		"app!=web":             "batch",
+		"app notin (web)":      "batch",
+		"app in (web),!canary": "web-1",
+	} {
+		s, err := ParseSelector(selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, o := range selectObjects(objects, s) {
+			names = append(names, o.Name)
+		}
+		if got := strings.Join(names, ","); got != want {
+			t.Errorf("%q selects %s, want %s", selector, got, want)
+		}
+	}
+	if objects["default/batch"].Labels == nil {
+		t.Error("an unlabeled object has no label map")
+	}
+}
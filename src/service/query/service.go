@@ -0,0 +1,87 @@
+package query
+
+import (
+	model "model/collect"
+
+	"github.com/astaxie/beego/orm"
+)
+
+type ServiceHealth struct {
+	Namespace           string `json:"namespace"`
+	Service_name        string `json:"service_name"`
+	Type                string `json:"type"`
+	Selector            string `json:"selector"`
+	Ready_addresses     int    `json:"ready_addresses"`
+	Not_ready_addresses int    `json:"not_ready_addresses"`
+	Not_ready_pods      string `json:"not_ready_pods"`
+}
+
+// UnreadyServices lists the services of a run that have no ready endpoint.
+// ExternalName services have no endpoints and are left out. When the run has
+// no endpoints to go by the readiness is unknown and an IncompleteError is
+// returned rather than every service.
+func UnreadyServices(runRef string) (*RunSet, []ServiceHealth, error) {
+	run, err := resolveRunOrLatest(runRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	o := orm.NewOrm()
+	var results []model.RunResults
+	if _, err := o.QueryTable("run_results").Filter("tag__in", run.Tags()).Filter("kind", "endpoints").All(&results); err != nil {
+		return nil, nil, err
+	}
+	if err := endpointsCollected(run.Tags(), results); err != nil {
+		return nil, nil, err
+	}
+	var services []model.Services
+	if _, err := o.QueryTable("services").Filter("tag__in", run.Tags()).OrderBy("namespace", "Service_name").Limit(-1).All(&services); err != nil {
+		return nil, nil, err
+	}
+	var endpoints []model.ServiceEndpoints
+	if _, err := o.QueryTable("service_endpoints").Filter("tag__in", run.Tags()).Limit(-1).All(&endpoints); err != nil {
+		return nil, nil, err
+	}
+	byService := make(map[string]model.ServiceEndpoints, len(endpoints))
+	for _, e := range endpoints {
+		byService[e.Namespace+"/"+e.Service_name] = e
+	}
+	unready := []ServiceHealth{}
+	for _, s := range services {
+		if model.ServiceType(s.Type) == model.ServiceTypeExternalName {
+			continue
+		}
+		e := byService[s.Namespace+"/"+s.Service_name]
+		if e.Ready_addresses > 0 {
+			continue
+		}
+		unready = append(unready, ServiceHealth{
+			Namespace:           s.Namespace,
+			Service_name:        s.Service_name,
+			Type:                s.Type,
+			Selector:            s.Selector,
+			Ready_addresses:     e.Ready_addresses,
+			Not_ready_addresses: e.Not_ready_addresses,
+			Not_ready_pods:      e.Not_ready_pods,
+		})
+	}
+	return run, unready, nil
+}
+
+// endpointsCollected fails unless the endpoints collector succeeded in the
+// run of every tag.
+func endpointsCollected(tags []string, results []model.RunResults) error {
+	status := make(map[string]string, len(results))
+	for _, r := range results {
+		status[r.Tag] = r.Status
+	}
+	for _, tag := range tags {
+		switch s := status[tag]; s {
+		case model.CollectorOk:
+		case "":
+			return IncompleteError("service readiness is unknown: run " + tag + " has no endpoints result")
+		default:
+			return IncompleteError("service readiness is unknown: the endpoints collector was " + s + " in run " + tag)
+		}
+	}
+	return nil
+}
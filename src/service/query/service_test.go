@@ -0,0 +1,23 @@
+package query
+
+import (
+	model "model/collect"
+	"testing"
+)
+
+func TestEndpointsCollected(t *testing.T) {
+	results := []model.RunResults{
+		{Tag: "a", Kind: "endpoints", Status: model.CollectorOk},
+		{Tag: "b", Kind: "endpoints", Status: model.CollectorFailed},
+		{Tag: "c", Kind: "endpoints", Status: model.CollectorDisabled},
+	}
+	if err := endpointsCollected([]string{"a"}, results); err != nil {
+		t.Error(err)
+	}
+	for _, tags := range [][]string{{"b"}, {"c"}, {"a", "b"}, {"d"}} {
+		err := endpointsCollected(tags, results)
+		if _, ok := err.(IncompleteError); !ok {
+			t.Errorf("runs %v: %v", tags, err)
+		}
+	}
+}
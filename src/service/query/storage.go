@@ -0,0 +1,80 @@
+package query
+
+import (
+	model "model/collect"
+
+	"github.com/astaxie/beego/orm"
+)
+
+type ClaimUsage struct {
+	model.PersistentVolumeClaims
+	Mounted_by []string `json:"mounted_by"`
+}
+
+type StorageReport struct {
+	Run              *RunSet                        `json:"run"`
+	Released_volumes []model.PersistentVolumes      `json:"released_volumes"`
+	Failed_volumes   []model.PersistentVolumes      `json:"failed_volumes"`
+	Pending_claims   []model.PersistentVolumeClaims `json:"pending_claims"`
+	Unmounted_claims []model.PersistentVolumeClaims `json:"unmounted_claims"`
+	Claims           []ClaimUsage                   `json:"claims"`
+}
+
+// Storage reports the volumes and claims of a run: Released and Failed
+// volumes, Pending claims, bound claims no pod mounts, and the pods mounting
+// each claim.
+func Storage(runRef string) (*StorageReport, error) {
+	run, err := resolveRunOrLatest(runRef)
+	if err != nil {
+		return nil, err
+	}
+	o := orm.NewOrm()
+	var volumes []model.PersistentVolumes
+	if _, err := o.QueryTable("persistent_volumes").Filter("tag__in", run.Tags()).Limit(-1).All(&volumes); err != nil {
+		return nil, err
+	}
+	var claims []model.PersistentVolumeClaims
+	if _, err := o.QueryTable("persistent_volume_claims").Filter("tag__in", run.Tags()).OrderBy("namespace", "claim_name").Limit(-1).All(&claims); err != nil {
+		return nil, err
+	}
+	var mounts []model.PodVolumeClaims
+	if _, err := o.QueryTable("pod_volume_claims").Filter("tag__in", run.Tags()).Limit(-1).All(&mounts); err != nil {
+		return nil, err
+	}
+
+	report := &StorageReport{
+		Run:              run,
+		Released_volumes: []model.PersistentVolumes{},
+		Failed_volumes:   []model.PersistentVolumes{},
+		Pending_claims:   []model.PersistentVolumeClaims{},
+		Unmounted_claims: []model.PersistentVolumeClaims{},
+		Claims:           []ClaimUsage{},
+	}
+	for _, v := range volumes {
+		switch model.PersistentVolumePhase(v.Phase) {
+		case model.VolumeReleased:
+			report.Released_volumes = append(report.Released_volumes, v)
+		case model.VolumeFailed:
+			report.Failed_volumes = append(report.Failed_volumes, v)
+		}
+	}
+	mountedBy := make(map[string][]string)
+	for _, m := range mounts {
+		key := m.Namespace + "/" + m.Claim_name
+		mountedBy[key] = append(mountedBy[key], m.Pod_name)
+	}
+	for _, c := range claims {
+		pods := mountedBy[c.Namespace+"/"+c.Claim_name]
+		if pods == nil {
+			pods = []string{}
+		}
+		report.Claims = append(report.Claims, ClaimUsage{PersistentVolumeClaims: c, Mounted_by: pods})
+		switch {
+		case model.PersistentVolumeClaimPhase(c.Phase) == model.ClaimPending:
+			report.Pending_claims = append(report.Pending_claims, c)
+		case len(pods) == 0:
+			report.Unmounted_claims = append(report.Unmounted_claims, c)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,147 @@
+package query
+
+import (
+	"common"
+	model "model/collect"
+	"time"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Workload is a workload row with the pods it controls, directly or through
+// the ReplicaSets or ReplicationControllers it owns.
+type Workload struct {
+	model.Workloads
+	Pods []string `json:"pods"`
+}
+
+// StuckRollout is a workload that has not converged for longer than the
+// report threshold, or whose rollout the controller gave up on.
+type StuckRollout struct {
+	Workload
+	Since string `json:"since"`
+}
+
+// Workloads lists the workloads of a run, optionally of one kind or
+// namespace, with their pods.
+func Workloads(runRef string, kind string, namespace string) (*RunSet, []Workload, error) {
+	run, err := resolveRunOrLatest(runRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	workloads, err := loadWorkloads(run.Tags())
+	if err != nil {
+		return nil, nil, err
+	}
+	rows := []Workload{}
+	for _, w := range workloads {
+		if (kind == "" || w.Kind == kind) && (namespace == "" || w.Namespace == namespace) {
+			rows = append(rows, w)
+		}
+	}
+	return run, rows, nil
+}
+
+// StuckRollouts lists the workloads of a run that have been rolling out for
+// at least after. Jobs and paused deployments are left out; deployments past
+// their progress deadline are reported at once.
+func StuckRollouts(runRef string, after time.Duration) (*RunSet, []StuckRollout, error) {
+	run, err := resolveRunOrLatest(runRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	workloads, err := loadWorkloads(run.Tags())
+	if err != nil {
+		return nil, nil, err
+	}
+	stuck := []StuckRollout{}
+	for _, w := range workloads {
+		if w.Converged || w.Paused || w.Kind == "Job" {
+			continue
+		}
+		history, err := rolloutHistory(w.Workloads)
+		if err != nil {
+			return nil, nil, err
+		}
+		since := rolloutSince(history, w.Workloads)
+		t, err := common.ParseRecordTime(since)
+		if err != nil {
+			return nil, nil, err
+		}
+		seen, err := common.ParseRecordTime(w.Record_time)
+		if err != nil {
+			return nil, nil, err
+		}
+		if seen.Sub(t) >= after || w.Reason == "ProgressDeadlineExceeded" {
+			stuck = append(stuck, StuckRollout{Workload: w, Since: since})
+		}
+	}
+	return run, stuck, nil
+}
+
+// rolloutHistory loads the rows of a workload from its last converged row up
+// to current, the row of the run being reported, oldest first.
+func rolloutHistory(current model.Workloads) ([]model.Workloads, error) {
+	qs := orm.NewOrm().QueryTable("workloads").Filter("uid", current.Uid).Filter("Record_time__lte", current.Record_time)
+	var last model.Workloads
+	err := qs.Filter("converged", true).OrderBy("-Record_time").One(&last, "Record_time")
+	if err == nil {
+		qs = qs.Filter("Record_time__gte", last.Record_time)
+	} else if err != orm.ErrNoRows {
+		return nil, err
+	}
+	var history []model.Workloads
+	_, err = qs.OrderBy("Record_time").Limit(-1).All(&history, "Record_time", "Converged")
+	return history, err
+}
+
+// rolloutSince returns when the current rollout of a workload was first seen:
+// the first row after the last converged one. A workload that stopped
+// converging in the run of current rolls out since then.
+func rolloutSince(history []model.Workloads, current model.Workloads) string {
+	since := current.Record_time
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Converged {
+			break
+		}
+		since = history[i].Record_time
+	}
+	return since
+}
+
+func loadWorkloads(tags []string) ([]Workload, error) {
+	o := orm.NewOrm()
+	var workloads []model.Workloads
+	if _, err := o.QueryTable("workloads").Filter("tag__in", tags).OrderBy("kind", "namespace", "workload_name").Limit(-1).All(&workloads); err != nil {
+		return nil, err
+	}
+	var pods []model.Pods
+	if _, err := o.QueryTable("pods").Filter("tag__in", tags).Exclude("owner_uid", "").Limit(-1).All(&pods, "Namespace", "Pod_name", "Owner_uid"); err != nil {
+		return nil, err
+	}
+	return linkPods(workloads, pods), nil
+}
+
+// linkPods attaches pods to their controller and to the owner of that
+// controller, so a Deployment lists the pods of its ReplicaSets.
+func linkPods(workloads []model.Workloads, pods []model.Pods) []Workload {
+	owner := make(map[string]string, len(workloads))
+	for _, w := range workloads {
+		owner[w.Uid] = w.Owner_uid
+	}
+	byUid := make(map[string][]string)
+	for _, p := range pods {
+		name := p.Namespace + "/" + p.Pod_name
+		for uid, depth := p.Owner_uid, 0; uid != "" && depth < 2; uid, depth = owner[uid], depth+1 {
+			byUid[uid] = append(byUid[uid], name)
+		}
+	}
+	rows := make([]Workload, len(workloads))
+	for i, w := range workloads {
+		rows[i] = Workload{Workloads: w, Pods: byUid[w.Uid]}
+		if rows[i].Pods == nil {
+			rows[i].Pods = []string{}
+		}
+	}
+	return rows
+}
@@ -0,0 +1,58 @@
+package query
+
+import (
+	model "model/collect"
+	"testing"
+)
+
+func TestLinkPods(t *testing.T) {
+	workloads := []model.Workloads{
+		{Kind: "Deployment", Workload_name: "web", Uid: "d1"},
+		{Kind: "ReplicaSet", Workload_name: "web-1", Uid: "r1", Owner_uid: "d1"},
+		{Kind: "ReplicaSet", Workload_name: "web-2", Uid: "r2", Owner_uid: "d1"},
+		{Kind: "StatefulSet", Workload_name: "db", Uid: "s1"},
+	}
+	pods := []model.Pods{
+		{Namespace: "default", Pod_name: "web-1-a", Owner_uid: "r1"},
+		{Namespace: "default", Pod_name: "web-2-a", Owner_uid: "r2"},
+		{Namespace: "default", Pod_name: "db-0", Owner_uid: "s1"},
+	}
+	rows := linkPods(workloads, pods)
+	want := map[string]int{"web": 2, "web-1": 1, "web-2": 1, "db": 1}
+	for _, row := range rows {
+		if len(row.Pods) != want[row.Workload_name] {
+			t.Errorf("%s pods = %v", row.Workload_name, row.Pods)
+		}
+	}
+}
+
+func TestRolloutSince(t *testing.T) {
+	current := model.Workloads{Record_time: "2017-03-01 10:20:00"}
+	for _, c := range []struct {
+		name    string
+		history []model.Workloads
+		want    string
+	}{
+		{"stopped converging in the latest run", []model.Workloads{
+			{Record_time: "2017-03-01 10:00:00", Converged: true},
+			{Record_time: "2017-03-01 10:10:00", Converged: true},
+			{Record_time: "2017-03-01 10:20:00"},
+		}, "2017-03-01 10:20:00"},
+		{"latest row not in the history", []model.Workloads{
+			{Record_time: "2017-03-01 10:10:00", Converged: true},
+		}, "2017-03-01 10:20:00"},
+		{"rolling out for two runs", []model.Workloads{
+			{Record_time: "2017-03-01 10:00:00", Converged: true},
+			{Record_time: "2017-03-01 10:10:00"},
+			{Record_time: "2017-03-01 10:20:00"},
+		}, "2017-03-01 10:10:00"},
+		{"never converged", []model.Workloads{
+			{Record_time: "2017-03-01 10:00:00"},
+			{Record_time: "2017-03-01 10:20:00"},
+		}, "2017-03-01 10:00:00"},
+	} {
+		if got := rolloutSince(c.history, current); got != c.want {
+			t.Errorf("%s: since %s, want %s", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package shard
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// virtualNodes is the number of points each member has on the ring, enough
+// to spread a few hundred namespaces evenly over a handful of members.
+const virtualNodes = 128
+
+// Ring assigns keys to members by consistent hashing: a key belongs to the
+// first member point at or after its hash. A member joining or leaving only
+// moves the keys next to its own points.
+type Ring struct {
+	points  []uint32
+	owners  map[uint32]string
+	members []string
+}
+
+func NewRing(members []string) *Ring {
+	r := &Ring{owners: make(map[uint32]string)}
+	r.members = append([]string{}, members...)
+	sort.Strings(r.members)
+	for _, m := range r.members {
+		for i := 0; i < virtualNodes; i++ {
+			h := crc32.ChecksumIEEE([]byte(m + "#" + strconv.Itoa(i)))
+			if _, taken := r.owners[h]; taken {
+				continue
+			}
+			r.owners[h] = m
+			r.points = append(r.points, h)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// Owner is the member key belongs to, empty when the ring has no members.
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+func (r *Ring) Members() []string {
+	return append([]string{}, r.members...)
+}
@@ -0,0 +1,133 @@
+// Package shard splits the namespaces of a cluster between collector
+// replicas. The replicas find each other through heartbeats in the store and
+// hash namespaces onto the live ones; the owner of clusterScopedKey also
+// collects the kinds that live outside namespaces.
+package shard
+
+import (
+	"common"
+	"dao"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// clusterScopedKey is hashed like a namespace to pick the owner of nodes,
+// namespaces and the other cluster-scoped kinds.
+const clusterScopedKey = "\x00cluster-scoped"
+
+// Members is where the replicas announce themselves.
+type Members interface {
+	Heartbeat(identity string, now string) error
+	Alive(since string) ([]string, error)
+	Leave(identity string) error
+}
+
+// DbMembers keeps the members in the collector_members table.
+type DbMembers struct{}
+
+func (DbMembers) Heartbeat(identity string, now string) error { return dao.Db_heartbeat(identity, now) }
+func (DbMembers) Alive(since string) ([]string, error)        { return dao.Db_aliveMembers(since) }
+func (DbMembers) Leave(identity string) error                 { return dao.Db_leaveMembers(identity) }
+
+// Status is what the status endpoint shows.
+type Status struct {
+	Identity       string   `json:"identity"`
+	Members        []string `json:"members"`
+	Cluster_scoped bool     `json:"cluster_scoped"`
+	Rebalanced     string   `json:"rebalanced,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// Shard is the share of one replica. Until its first heartbeat made it into
+// the store it owns nothing, so a replica cut off from the store does not
+// collect what the others collect.
+type Shard struct {
+	Identity string
+	Members  Members
+	// Every is the heartbeat period; a member is gone after TTL without one.
+	Every time.Duration
+	TTL   time.Duration
+
+	lock       sync.Mutex
+	ring       *Ring
+	rebalanced time.Time
+	err        error
+}
+
+func New(identity string, members Members) *Shard {
+	return &Shard{Identity: identity, Members: members, Every: 10 * time.Second, TTL: 30 * time.Second, ring: NewRing(nil)}
+}
+
+// Run heartbeats and refreshes the members every Every until stop is closed,
+// then leaves.
+func (s *Shard) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.Every)
+	defer ticker.Stop()
+	for {
+		s.refresh(time.Now())
+		select {
+		case <-stop:
+			common.LogErr(s.Members.Leave(s.Identity))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Shard) refresh(now time.Time) {
+	err := s.Members.Heartbeat(s.Identity, common.RecordTime(now))
+	var members []string
+	if err == nil {
+		members, err = s.Members.Alive(common.RecordTime(now.Add(-s.TTL)))
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err
+	if err != nil {
+		// keep the last ring, the others are likely in the same situation
+		common.LogErr(err)
+		return
+	}
+	if reflect.DeepEqual(members, s.ring.Members()) {
+		return
+	}
+	common.DebugPrint("shard: members", s.ring.Members(), "became", members)
+	s.ring = NewRing(members)
+	s.rebalanced = now
+}
+
+// OwnsNamespace reports whether this replica collects namespace.
+func (s *Shard) OwnsNamespace(namespace string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.ring.Owner(namespace) == s.Identity
+}
+
+// OwnsClusterScoped reports whether this replica collects the kinds outside
+// namespaces.
+func (s *Shard) OwnsClusterScoped() bool {
+	return s.OwnsNamespace(clusterScopedKey)
+}
+
+// Describe names the shard in the runs it collects.
+func (s *Shard) Describe() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.Identity + " of " + strconv.Itoa(len(s.ring.Members()))
+}
+
+func (s *Shard) Status() Status {
+	st := Status{Identity: s.Identity, Cluster_scoped: s.OwnsClusterScoped()}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st.Members = s.ring.Members()
+	if !s.rebalanced.IsZero() {
+		st.Rebalanced = common.RecordTime(s.rebalanced)
+	}
+	if s.err != nil {
+		st.Error = s.err.Error()
+	}
+	return st
+}
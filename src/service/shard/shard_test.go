@@ -0,0 +1,92 @@
+package shard
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRing(t *testing.T) {
+	var namespaces []string
+	for i := 0; i < 1000; i++ {
+		namespaces = append(namespaces, "ns-"+strconv.Itoa(i))
+	}
+	three := NewRing([]string{"a", "b", "c"})
+	counts := make(map[string]int)
+	for _, ns := range namespaces {
+		counts[three.Owner(ns)]++
+	}
+	for _, m := range []string{"a", "b", "c"} {
+		if counts[m] < 200 || counts[m] > 470 {
+			t.Errorf("%s owns %d of 1000 namespaces", m, counts[m])
+		}
+	}
+
+	// a fourth member only takes namespaces over, the others keep theirs
+	four := NewRing([]string{"a", "b", "c", "d"})
+	moved := 0
+	for _, ns := range namespaces {
+		if before, after := three.Owner(ns), four.Owner(ns); before != after {
+			moved++
+			if after != "d" {
+				t.Errorf("%s moved from %s to %s", ns, before, after)
+			}
+		}
+	}
+	if moved == 0 || moved > 400 {
+		t.Errorf("%d namespaces moved", moved)
+	}
+	if NewRing(nil).Owner("default") != "" {
+		t.Error("an empty ring has an owner")
+	}
+}
+
+// memMembers keeps heartbeats in a map.
+type memMembers map[string]string
+
+func (m memMembers) Heartbeat(identity string, now string) error { m[identity] = now; return nil }
+func (m memMembers) Leave(identity string) error                 { delete(m, identity); return nil }
+func (m memMembers) Alive(since string) ([]string, error) {
+	var alive []string
+	for id, beat := range m {
+		if beat >= since {
+			alive = append(alive, id)
+		}
+	}
+	sort.Strings(alive)
+	return alive, nil
+}
+
+func TestShardRebalance(t *testing.T) {
+	members := memMembers{}
+	a, b := New("a", members), New("b", members)
+	if a.OwnsNamespace("default") || a.OwnsClusterScoped() {
+		t.Fatal("a shard owns namespaces before its first heartbeat")
+	}
+	now := time.Now()
+	a.refresh(now)
+	if !a.OwnsNamespace("default") || !a.OwnsClusterScoped() {
+		t.Fatal("a lone member does not own everything")
+	}
+	b.refresh(now)
+	a.refresh(now)
+	owners := 0
+	for _, s := range []*Shard{a, b} {
+		if s.OwnsClusterScoped() {
+			owners++
+		}
+		if st := s.Status(); len(st.Members) != 2 {
+			t.Errorf("%s sees %v", s.Identity, st.Members)
+		}
+	}
+	if owners != 1 {
+		t.Errorf("%d owners of the cluster-scoped kinds", owners)
+	}
+
+	// b stops heartbeating and drops out after the TTL
+	a.refresh(now.Add(a.TTL + time.Second))
+	if st := a.Status(); len(st.Members) != 1 || !a.OwnsNamespace("default") {
+		t.Errorf("a after b left: %+v", st)
+	}
+}